@@ -1,47 +1,337 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"mf/internal/migration"
+	"mf/internal/otpauth"
+	"mf/internal/qrcode"
 	"mf/internal/storage"
 	"mf/internal/totp"
 	"mf/internal/types"
 )
 
+var (
+	addURI       string
+	addQR        string
+	addEncoding  string
+	addPIN       string
+	addSuite     string
+	addType      string
+	addCounter   uint64
+	addAlgorithm string
+	addDigits    int
+	addPeriod    int
+	addT0        int64
+)
+
 var addCmd = &cobra.Command{
-	Use:   "add [ACCOUNT_NAME] [SECRET]",
-	Short: "Adiciona uma nova conta para geração de tokens TOTP",
-	Long:  `Adiciona uma nova conta com o nome especificado e o secret fornecido para geração de tokens TOTP.`,
-	Args:  cobra.ExactArgs(2),
+	Use:   "add [ACCOUNT_NAME] [SECRET|URI]",
+	Short: "Adiciona uma nova conta para geração de tokens TOTP ou HOTP",
+	Long: `Adiciona uma nova conta com o nome especificado e o secret fornecido para geração de tokens TOTP.
+
+Os parâmetros do TOTP (algoritmo, dígitos, período e T0) podem ser ajustados
+para provedores que não usam o padrão SHA1/6 dígitos/30 segundos.
+Contas HOTP (--type hotp) usam um contador em vez do horário e contas
+Steam Guard (--type steam) geram códigos de 5 caracteres no alfabeto do Steam.
+Contas mOTP (--type motp) usam o secret em hexadecimal e um PIN, que pode
+ser salvo com --pin ou pedido a cada uso. Contas Yandex Key (--type yandex)
+também usam PIN e geram códigos de 8 letras. Contas OCRA (--type ocra, com
+--suite) respondem a desafios com mf ocra.
+
+Também é possível informar uma URI otpauth:// no lugar do secret, via --uri
+ou pela entrada padrão (--uri -, ou sem argumentos). Nesse caso o nome, o
+emissor e os parâmetros são extraídos da URI; um ACCOUNT_NAME informado
+substitui o nome derivado.
+
+O secret pode ser digitado como o provedor o exibe (com espaços, em
+minúsculas, sem padding); com --encoding hex ou base64 ele é convertido
+para base32.
+
+Com --qr a URI é lida do QR code de uma imagem PNG, JPEG ou GIF. Se a imagem
+tiver vários QR codes, todas as contas encontradas são adicionadas.`,
+	Example: `  mf add GITHUB JBSWY3DPEHPK3PXP
+  mf add GITHUB 'jbsw y3dp ehpk 3pxp'
+  mf add TOKEN 3132333435363738393031323334353637383930 --encoding hex
+  mf add 'otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub'
+  mf add GITHUB --uri 'otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP'
+  echo 'otpauth://totp/...' | mf add
+  mf add --qr screenshot.png
+  mf add BANK GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ --type ocra --suite OCRA-1:HOTP-SHA1-6:QN08`,
+	Args: cobra.MaximumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		accountName := args[0]
-		secret := args[1]
+		if addQR != "" {
+			return addFromQR(cmd, args)
+		}
 
-		if err := totp.ValidateSecret(secret); err != nil {
-			return fmt.Errorf("secret inválido: %w", err)
+		name, uri, err := resolveAddInput(cmd, args)
+		if err != nil {
+			return err
 		}
 
-		store, err := storage.NewSecure()
+		if migration.IsURI(uri) {
+			if name != "" {
+				return fmt.Errorf("exportações do Google Authenticator não aceitam ACCOUNT_NAME")
+			}
+			accounts, err := accountsFromURIs([]string{uri})
+			if err != nil {
+				return err
+			}
+			return saveImportedAccounts(accounts)
+		}
+
+		account, err := accountFromAddInput(cmd, args, name, uri)
 		if err != nil {
-			return fmt.Errorf("erro ao inicializar storage: %w", err)
+			return err
 		}
 
-		account := types.Account{
-			Name:   accountName,
-			Secret: secret,
+		if err := validateAccount(account); err != nil {
+			return err
+		}
+
+		store, err := storage.NewSecure()
+		if err != nil {
+			return fmt.Errorf("erro ao inicializar storage: %w", err)
 		}
 
-		if err := store.SaveAccount(account); err != nil {
+		if err := store.SaveAccount(*account); err != nil {
 			return fmt.Errorf("erro ao salvar conta: %w", err)
 		}
 
-		fmt.Printf("Conta '%s' adicionada com sucesso.\n", accountName)
+		fmt.Printf("Conta '%s' adicionada com sucesso.\n", account.Name)
 		return nil
 	},
 }
 
+// addFromQR stores every account found in the QR codes of an image. A name
+// may only be given when the image holds a single account.
+func addFromQR(cmd *cobra.Command, args []string) error {
+	if addURI != "" {
+		return fmt.Errorf("--qr e --uri não podem ser usados juntos")
+	}
+	if len(args) > 1 {
+		return fmt.Errorf("com --qr informe no máximo o nome da conta")
+	}
+
+	uris, err := qrcode.DecodeFile(addQR)
+	if err != nil {
+		return fmt.Errorf("erro ao ler QR code: %w", err)
+	}
+
+	for _, uri := range uris {
+		if !isImportURI(uri) {
+			return fmt.Errorf("QR code não contém uma URI otpauth:// válida")
+		}
+	}
+
+	accounts, err := accountsFromURIs(uris)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		if len(accounts) != 1 {
+			return fmt.Errorf("a imagem contém %d contas; ACCOUNT_NAME só pode ser usado com uma", len(accounts))
+		}
+		accounts[0].Name = args[0]
+	}
+
+	return saveImportedAccounts(accounts)
+}
+
+// resolveAddInput works out whether mf add was given a name and secret or a
+// URI (as argument, via --uri or on stdin).
+func resolveAddInput(cmd *cobra.Command, args []string) (name, uri string, err error) {
+	uri = addURI
+
+	switch {
+	case uri != "":
+		if len(args) > 1 {
+			return "", "", fmt.Errorf("com --uri informe no máximo o nome da conta")
+		}
+		if len(args) == 1 {
+			name = args[0]
+		}
+	case len(args) > 0 && isImportURI(args[len(args)-1]):
+		uri = args[len(args)-1]
+		if len(args) == 2 {
+			name = args[0]
+		}
+	case len(args) == 0:
+		uri = "-"
+	case len(args) == 1:
+		return "", "", fmt.Errorf("informe o nome da conta e o secret, ou uma URI otpauth://")
+	}
+
+	if uri == "-" {
+		uri, err = readFirstLine(cmd.InOrStdin())
+		if err != nil {
+			return "", "", fmt.Errorf("erro ao ler URI da entrada padrão: %w", err)
+		}
+	}
+
+	return name, uri, nil
+}
+
+func accountFromAddInput(cmd *cobra.Command, args []string, name, uri string) (*types.Account, error) {
+	if uri == "" {
+		accountType := strings.ToLower(addType)
+
+		// mOTP secrets are hex strings used verbatim, not base32 keys.
+		secret := args[1]
+		if accountType != types.AccountTypeMOTP {
+			var err error
+			secret, err = totp.ConvertSecret(args[1], addEncoding)
+			if err != nil {
+				return nil, fmt.Errorf("secret inválido: %w", err)
+			}
+		}
+
+		return &types.Account{
+			Name:      args[0],
+			Secret:    secret,
+			Type:      accountType,
+			PIN:       addPIN,
+			Suite:     addSuite,
+			Counter:   addCounter,
+			Algorithm: strings.ToUpper(addAlgorithm),
+			Digits:    addDigits,
+			Period:    addPeriod,
+			T0:        addT0,
+		}, nil
+	}
+
+	account, err := otpauth.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("URI inválida: %w", err)
+	}
+	if name != "" {
+		account.Name = name
+	}
+
+	// Flags explicitly set on the command line take precedence over the URI.
+	flags := cmd.Flags()
+	if flags.Changed("type") {
+		account.Type = strings.ToLower(addType)
+	}
+	if flags.Changed("counter") {
+		account.Counter = addCounter
+	}
+	if flags.Changed("algorithm") {
+		account.Algorithm = strings.ToUpper(addAlgorithm)
+	}
+	if flags.Changed("digits") {
+		account.Digits = addDigits
+	}
+	if flags.Changed("period") {
+		account.Period = addPeriod
+	}
+	if flags.Changed("t0") {
+		account.T0 = addT0
+	}
+
+	return account, nil
+}
+
+func isImportURI(s string) bool {
+	return otpauth.IsURI(s) || migration.IsURI(s)
+}
+
+// validateAccount checks an account before it is stored and normalizes its
+// secret to canonical base32.
+func validateAccount(account *types.Account) error {
+	if account.Name == "" {
+		return fmt.Errorf("nome da conta não pode ser vazio")
+	}
+
+	switch account.AccountType() {
+	case types.AccountTypeTOTP, types.AccountTypeHOTP, types.AccountTypeSteam, types.AccountTypeMOTP, types.AccountTypeYandex, types.AccountTypeOCRA:
+	default:
+		return fmt.Errorf("tipo de conta inválido: %s", account.Type)
+	}
+
+	if account.AccountType() == types.AccountTypeMOTP {
+		secret, err := totp.NormalizeMOTPSecret(account.Secret)
+		if err != nil {
+			return fmt.Errorf("secret inválido: %w", err)
+		}
+		account.Secret = secret
+	} else {
+		secret, err := totp.NormalizeSecret(account.Secret)
+		if err != nil {
+			return fmt.Errorf("secret inválido: %w", err)
+		}
+		if err := totp.ValidateSecret(secret); err != nil {
+			return fmt.Errorf("secret inválido: %w", err)
+		}
+		if account.AccountType() == types.AccountTypeYandex {
+			if err := totp.ValidateYandexSecret(secret); err != nil {
+				return fmt.Errorf("secret inválido: %w", err)
+			}
+		}
+		account.Secret = secret
+	}
+
+	if account.PIN != "" {
+		if !account.NeedsPIN() {
+			return fmt.Errorf("contas do tipo %s não usam PIN", account.AccountType())
+		}
+		validatePIN := totp.ValidatePIN
+		if account.AccountType() == types.AccountTypeYandex {
+			validatePIN = totp.ValidateYandexPIN
+		}
+		if err := validatePIN(account.PIN); err != nil {
+			return fmt.Errorf("PIN inválido: %w", err)
+		}
+	}
+
+	if account.AccountType() == types.AccountTypeOCRA {
+		if account.Suite == "" {
+			return fmt.Errorf("contas OCRA exigem --suite")
+		}
+		if _, err := totp.ParseOCRASuite(account.Suite); err != nil {
+			return fmt.Errorf("suite OCRA inválida: %w", err)
+		}
+	} else if account.Suite != "" {
+		return fmt.Errorf("--suite só se aplica a contas OCRA")
+	}
+
+	if err := tokenParams(account).Validate(); err != nil {
+		return fmt.Errorf("parâmetros TOTP inválidos: %w", err)
+	}
+
+	return nil
+}
+
+func readFirstLine(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			return line, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
 func init() {
+	addCmd.Flags().StringVar(&addURI, "uri", "", "URI otpauth:// da conta (use - para ler da entrada padrão)")
+	addCmd.Flags().StringVar(&addQR, "qr", "", "imagem (PNG, JPEG ou GIF) com o QR code da conta")
+	addCmd.Flags().StringVar(&addEncoding, "encoding", totp.EncodingBase32, "codificação do secret (base32, hex ou base64)")
+	addCmd.Flags().StringVar(&addPIN, "pin", "", "PIN de contas mOTP e Yandex (se omitido, é pedido a cada uso)")
+	addCmd.Flags().StringVar(&addType, "type", types.AccountTypeTOTP, "tipo de conta (totp, hotp, steam, motp, yandex ou ocra)")
+	addCmd.Flags().StringVar(&addSuite, "suite", "", "suite OCRA (RFC 6287) de contas OCRA, ex.: OCRA-1:HOTP-SHA1-6:QN08")
+	addCmd.Flags().Uint64Var(&addCounter, "counter", 0, "contador inicial para contas HOTP e OCRA")
+	addCmd.Flags().StringVar(&addAlgorithm, "algorithm", totp.DefaultAlgorithm, "algoritmo HMAC (SHA1, SHA256 ou SHA512)")
+	addCmd.Flags().IntVar(&addDigits, "digits", totp.DefaultDigits, "número de dígitos do token (6 a 8)")
+	addCmd.Flags().IntVar(&addPeriod, "period", totp.DefaultPeriod, "período de validade do token em segundos")
+	addCmd.Flags().Int64Var(&addT0, "t0", 0, "instante inicial da contagem (Unix timestamp)")
 	rootCmd.AddCommand(addCmd)
 }
@@ -2,18 +2,47 @@ package cmd
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"mf/internal/config"
 	"mf/internal/storage"
 	"mf/internal/totp"
+	"mf/internal/types"
+)
+
+var (
+	getRemaining bool
+	getNext      bool
+	getPrevious  bool
+	getCount     int
+	getAt        string
+	getMinValid  time.Duration
 )
 
 var getCmd = &cobra.Command{
 	Use:   "get [ACCOUNT_NAME]",
-	Short: "Gera um token TOTP para a conta especificada",
-	Long:  `Gera um token TOTP (Time-based One-Time Password) para a conta especificada.`,
-	Args:  cobra.ExactArgs(1),
+	Short: "Gera um token TOTP ou HOTP para a conta especificada",
+	Long: `Gera um token TOTP (Time-based One-Time Password) para a conta especificada.
+
+Para contas HOTP o contador é incrementado e salvo a cada token gerado.
+
+Para contas baseadas em tempo, --remaining mostra quantos segundos o código
+ainda é válido, --next/--previous (com --count N) listam os códigos vizinhos
+com seus intervalos de validade e --at gera o código para outro instante
+(RFC3339 ou Unix timestamp).
+
+Com --min-validity, se o código atual expirar antes do tempo informado, o mf
+aguarda o início do próximo período e retorna o novo código.`,
+	Example: `  mf get GITHUB
+  mf get GITHUB --remaining
+  mf get GITHUB --next --previous --count 2
+  mf get GITHUB --at 2025-01-01T12:00:00Z
+  mf get GITHUB --min-validity 5s`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		accountName := args[0]
 
@@ -27,16 +56,197 @@ var getCmd = &cobra.Command{
 			return fmt.Errorf("erro ao carregar conta: %w", err)
 		}
 
-		token, err := totp.GenerateToken(account.Secret)
+		timingFlags := getRemaining || getNext || getPrevious || getAt != "" ||
+			cmd.Flags().Changed("count") || getMinValid > 0
+
+		if account.AccountType() == types.AccountTypeOCRA {
+			return fmt.Errorf("contas OCRA respondem a desafios: use mf ocra %s --challenge ...", accountName)
+		}
+
+		if account.AccountType() == types.AccountTypeHOTP {
+			if timingFlags {
+				return fmt.Errorf("opções de tempo não se aplicam a contas HOTP")
+			}
+
+			// The counter is persisted before the token is shown so a code is
+			// never handed out twice for the same counter value.
+			var token string
+			_, err = store.UpdateAccount(accountName, func(a *types.Account) error {
+				var genErr error
+				token, genErr = totp.GenerateHOTP(a.Secret, a.Counter, tokenParams(a))
+				if genErr != nil {
+					return genErr
+				}
+				a.Counter++
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("erro ao gerar token: %w", err)
+			}
+
+			fmt.Println(token)
+			return nil
+		}
+
+		if err := ensurePIN(cmd, account); err != nil {
+			return err
+		}
+
+		clock, err := accountClock(account)
+		if err != nil {
+			return err
+		}
+
+		at := clock.Now()
+		if getAt != "" {
+			at, err = parseTimestamp(getAt)
+			if err != nil {
+				return err
+			}
+		}
+
+		if getMinValid > 0 {
+			period := accountPeriod(account)
+			if getMinValid >= time.Duration(period)*time.Second {
+				return fmt.Errorf("--min-validity deve ser menor que o período da conta (%ds)", period)
+			}
+
+			fresh := totp.FreshAt(at, period, account.T0, getMinValid)
+			if fresh.After(at) && getAt == "" {
+				wait := fresh.Sub(at)
+				fmt.Fprintf(cmd.ErrOrStderr(), "Aguardando %s pelo próximo código...\n", wait.Round(time.Second))
+				time.Sleep(wait)
+			}
+			at = fresh
+		}
+
+		if getNext || getPrevious || cmd.Flags().Changed("count") {
+			if getCount < 1 {
+				return fmt.Errorf("--count deve ser maior que zero")
+			}
+			return printCodeList(account, at)
+		}
+
+		token, err := generateCode(account, at)
 		if err != nil {
 			return fmt.Errorf("erro ao gerar token: %w", err)
 		}
 
+		if getRemaining {
+			remaining := totp.Remaining(at, accountPeriod(account), account.T0)
+			fmt.Printf("%s\t%d\n", token, int(math.Ceil(remaining.Seconds())))
+			return nil
+		}
+
 		fmt.Println(token)
 		return nil
 	},
 }
 
+// printCodeList prints the codes around at, one step per line, with the
+// interval each one is valid for. Without --previous only upcoming codes are
+// listed.
+func printCodeList(account *types.Account, at time.Time) error {
+	period := accountPeriod(account)
+	step := time.Duration(period) * time.Second
+
+	first, last := 0, getCount
+	if getPrevious {
+		first = -getCount
+		if !getNext {
+			last = 0
+		}
+	}
+
+	for offset := first; offset <= last; offset++ {
+		t := at.Add(time.Duration(offset) * step)
+		token, err := generateCode(account, t)
+		if err != nil {
+			return fmt.Errorf("erro ao gerar token: %w", err)
+		}
+
+		start, end := totp.StepBounds(t, period, account.T0)
+		line := fmt.Sprintf("%+3d  %s  %s - %s", offset, token,
+			start.Format("2006-01-02 15:04:05"), end.Format("15:04:05"))
+		if offset == 0 && getRemaining {
+			line += fmt.Sprintf("  (%ds)", int(math.Ceil(end.Sub(at).Seconds())))
+		}
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+// parseTimestamp accepts either an RFC3339 timestamp or Unix seconds.
+func parseTimestamp(value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("instante inválido '%s': use RFC3339 ou Unix timestamp", value)
+	}
+	return t, nil
+}
+
 func init() {
+	getCmd.Flags().BoolVar(&getRemaining, "remaining", false, "mostra os segundos restantes de validade do código")
+	getCmd.Flags().BoolVar(&getNext, "next", false, "lista os próximos códigos")
+	getCmd.Flags().BoolVar(&getPrevious, "previous", false, "lista os códigos anteriores")
+	getCmd.Flags().IntVar(&getCount, "count", 1, "quantidade de códigos listados com --next/--previous")
+	getCmd.Flags().StringVar(&getAt, "at", "", "gera o código para o instante informado (RFC3339 ou Unix timestamp)")
+	getCmd.Flags().DurationVar(&getMinValid, "min-validity", 0, "validade mínima do código; aguarda o próximo período se necessário (ex.: 5s)")
 	rootCmd.AddCommand(getCmd)
 }
+
+// generateCode returns the time-based code of an account at t.
+func generateCode(account *types.Account, t time.Time) (string, error) {
+	switch account.AccountType() {
+	case types.AccountTypeMOTP:
+		return totp.GenerateMOTP(account.Secret, account.PIN, t)
+	case types.AccountTypeYandex:
+		return totp.GenerateYandexToken(account.Secret, account.PIN, t)
+	}
+	return totp.GenerateTokenAt(account.Secret, tokenParams(account), t)
+}
+
+// accountPeriod returns the step length in seconds of a time-based account.
+func accountPeriod(account *types.Account) int {
+	if period := tokenParams(account).Period; period > 0 {
+		return period
+	}
+	return totp.DefaultPeriod
+}
+
+// accountClock returns the clock used for an account: the system clock
+// corrected by the global and the per-account offsets.
+func accountClock(account *types.Account) (totp.Clock, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar configuração: %w", err)
+	}
+
+	return totp.OffsetClock{
+		Base:   totp.SystemClock{},
+		Offset: cfg.TimeOffset() + account.TimeOffset(),
+	}, nil
+}
+
+func tokenParams(account *types.Account) totp.Params {
+	switch account.AccountType() {
+	case types.AccountTypeSteam:
+		return totp.SteamParams
+	case types.AccountTypeMOTP:
+		return totp.Params{Period: totp.MOTPPeriod}
+	case types.AccountTypeYandex:
+		return totp.Params{Period: totp.YandexPeriod}
+	}
+
+	return totp.Params{
+		Algorithm: account.Algorithm,
+		Digits:    account.Digits,
+		Period:    account.Period,
+		T0:        account.T0,
+	}
+}
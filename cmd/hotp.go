@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/storage"
+	"mf/internal/types"
+)
+
+var hotpCmd = &cobra.Command{
+	Use:   "hotp",
+	Short: "Gerencia contas HOTP (baseadas em contador)",
+	Long:  `Comandos auxiliares para contas HOTP (HMAC-based One-Time Password, RFC 4226).`,
+}
+
+var hotpSetCounterCmd = &cobra.Command{
+	Use:   "set-counter [ACCOUNT_NAME] [COUNTER]",
+	Short: "Define o contador de uma conta HOTP",
+	Long: `Define manualmente o próximo valor de contador de uma conta HOTP.
+
+Útil para ressincronizar o mf com o servidor quando tokens foram gerados
+sem serem usados.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		accountName := args[0]
+
+		counter, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("contador inválido: %s", args[1])
+		}
+
+		store, err := storage.NewSecure()
+		if err != nil {
+			return fmt.Errorf("erro ao inicializar storage: %w", err)
+		}
+
+		_, err = store.UpdateAccount(accountName, func(account *types.Account) error {
+			if account.AccountType() != types.AccountTypeHOTP {
+				return fmt.Errorf("a conta '%s' não é do tipo HOTP", accountName)
+			}
+			account.Counter = counter
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("erro ao atualizar contador: %w", err)
+		}
+
+		fmt.Printf("Contador da conta '%s' definido para %d.\n", accountName, counter)
+		return nil
+	},
+}
+
+func init() {
+	hotpCmd.AddCommand(hotpSetCounterCmd)
+	rootCmd.AddCommand(hotpCmd)
+}
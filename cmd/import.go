@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/migration"
+	"mf/internal/otpauth"
+	"mf/internal/storage"
+	"mf/internal/types"
+)
+
+var importURIsFile string
+
+var importCmd = &cobra.Command{
+	Use:   "import [URI...]",
+	Short: "Importa contas a partir de URIs otpauth:// ou exportações do Google Authenticator",
+	Long: `Importa contas a partir de URIs otpauth:// e otpauth-migration://.
+
+As URIs podem ser passadas como argumentos ou em um arquivo com uma URI por
+linha (--uris). Linhas em branco e linhas iniciadas por # são ignoradas.
+
+URIs otpauth-migration:// são as exportações do Google Authenticator
+("Transferir contas"). Exportações em vários QR codes devem incluir todos os
+lotes; a importação falha se algum estiver faltando.
+
+Todas as URIs são validadas antes de qualquer conta ser salva. Use - para
+ler o arquivo da entrada padrão.`,
+	Example: `  mf import --uris contas.txt
+  cat contas.txt | mf import --uris -
+  mf import 'otpauth-migration://offline?data=...'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if importURIsFile == "" && len(args) == 0 {
+			return fmt.Errorf("informe as URIs como argumentos ou o arquivo com --uris")
+		}
+
+		uris := args
+		if importURIsFile != "" {
+			input, err := openInput(cmd, importURIsFile)
+			if err != nil {
+				return fmt.Errorf("erro ao abrir arquivo: %w", err)
+			}
+			defer input.Close()
+
+			lines, err := readURIList(input)
+			if err != nil {
+				return fmt.Errorf("erro ao ler arquivo: %w", err)
+			}
+			uris = append(uris, lines...)
+		}
+
+		accounts, err := accountsFromURIs(uris)
+		if err != nil {
+			return err
+		}
+
+		if len(accounts) == 0 {
+			fmt.Println("Nenhuma conta encontrada.")
+			return nil
+		}
+
+		return saveImportedAccounts(accounts)
+	},
+}
+
+func readURIList(r io.Reader) ([]string, error) {
+	var uris []string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uris = append(uris, line)
+	}
+
+	return uris, scanner.Err()
+}
+
+// accountsFromURIs parses and validates otpauth:// and otpauth-migration://
+// URIs. Migration URIs are decoded together so multi-batch exports can be
+// checked for completeness.
+func accountsFromURIs(uris []string) ([]types.Account, error) {
+	var accounts []types.Account
+	var migrationURIs []string
+
+	for _, uri := range uris {
+		if migration.IsURI(uri) {
+			migrationURIs = append(migrationURIs, uri)
+			continue
+		}
+
+		account, err := otpauth.Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("URI inválida: %w", err)
+		}
+		accounts = append(accounts, *account)
+	}
+
+	if len(migrationURIs) > 0 {
+		migrated, err := migration.DecodeAll(migrationURIs)
+		if err != nil {
+			return nil, fmt.Errorf("exportação do Google Authenticator inválida: %w", err)
+		}
+		accounts = append(accounts, migrated...)
+	}
+
+	for i := range accounts {
+		if err := validateAccount(&accounts[i]); err != nil {
+			return nil, fmt.Errorf("conta '%s': %w", accounts[i].Name, err)
+		}
+	}
+
+	return accounts, nil
+}
+
+func saveImportedAccounts(accounts []types.Account) error {
+	store, err := storage.NewSecure()
+	if err != nil {
+		return fmt.Errorf("erro ao inicializar storage: %w", err)
+	}
+
+	if err := store.SaveAccounts(accounts); err != nil {
+		return fmt.Errorf("erro ao salvar contas: %w", err)
+	}
+
+	for _, account := range accounts {
+		fmt.Printf("Conta '%s' adicionada com sucesso.\n", account.Name)
+	}
+	fmt.Printf("%d conta(s) importada(s).\n", len(accounts))
+	return nil
+}
+
+func openInput(cmd *cobra.Command, path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(cmd.InOrStdin()), nil
+	}
+	return os.Open(path)
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importURIsFile, "uris", "", "arquivo com uma URI otpauth:// por linha (- para entrada padrão)")
+	rootCmd.AddCommand(importCmd)
+}
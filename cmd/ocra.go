@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/storage"
+	"mf/internal/totp"
+	"mf/internal/types"
+)
+
+var (
+	ocraChallenge string
+	ocraCounter   uint64
+	ocraPassword  string
+	ocraSession   string
+	ocraTimestamp string
+)
+
+var ocraCmd = &cobra.Command{
+	Use:   "ocra [ACCOUNT_NAME]",
+	Short: "Calcula a resposta OCRA (RFC 6287) para um desafio",
+	Long: `Calcula a resposta OCRA (OATH Challenge-Response Algorithm, RFC 6287) de
+uma conta do tipo ocra para o desafio informado com --challenge.
+
+Os demais dados usados dependem da suite da conta:
+  C  contador: o valor salvo na conta, incrementado a cada resposta, ou o
+     informado com --counter (que não altera o valor salvo)
+  P  senha: --password, ou pedida sem eco se omitida
+  S  informações de sessão em hexadecimal: --session
+  T  instante: o horário atual (com a correção de relógio) ou --timestamp
+     (RFC3339 ou Unix timestamp)`,
+	Example: `  mf ocra BANK --challenge 12345678
+  mf ocra BANK --challenge 12345678 --counter 5
+  mf ocra BANK --challenge SIG10000 --timestamp 2025-01-01T12:00:00Z`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		accountName := args[0]
+
+		if ocraChallenge == "" {
+			return fmt.Errorf("informe o desafio com --challenge")
+		}
+
+		store, err := storage.NewSecure()
+		if err != nil {
+			return fmt.Errorf("erro ao inicializar storage: %w", err)
+		}
+
+		account, err := store.LoadAccount(accountName)
+		if err != nil {
+			return fmt.Errorf("erro ao carregar conta: %w", err)
+		}
+		if account.AccountType() != types.AccountTypeOCRA {
+			return fmt.Errorf("a conta '%s' não é do tipo OCRA", accountName)
+		}
+
+		suite, err := totp.ParseOCRASuite(account.Suite)
+		if err != nil {
+			return fmt.Errorf("suite OCRA inválida: %w", err)
+		}
+
+		input := totp.OCRAInput{
+			Challenge: ocraChallenge,
+			Password:  ocraPassword,
+			Session:   ocraSession,
+		}
+
+		if suite.PasswordAlgorithm != "" && input.Password == "" {
+			input.Password, err = promptHidden(cmd, fmt.Sprintf("Senha da conta '%s': ", accountName))
+			if err != nil {
+				return fmt.Errorf("erro ao ler senha: %w", err)
+			}
+		}
+
+		if suite.TimeStep > 0 {
+			if ocraTimestamp != "" {
+				input.Time, err = parseTimestamp(ocraTimestamp)
+				if err != nil {
+					return err
+				}
+			} else {
+				clock, err := accountClock(account)
+				if err != nil {
+					return err
+				}
+				input.Time = clock.Now()
+			}
+		}
+
+		// Like HOTP, the stored counter is advanced before the response is
+		// shown unless an explicit counter was requested.
+		if suite.Counter && !cmd.Flags().Changed("counter") {
+			var response string
+			_, err = store.UpdateAccount(accountName, func(a *types.Account) error {
+				input.Counter = a.Counter
+				var genErr error
+				response, genErr = totp.GenerateOCRA(a.Secret, a.Suite, input)
+				if genErr != nil {
+					return genErr
+				}
+				a.Counter++
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("erro ao calcular resposta: %w", err)
+			}
+
+			fmt.Println(response)
+			return nil
+		}
+
+		input.Counter = ocraCounter
+		response, err := totp.GenerateOCRA(account.Secret, account.Suite, input)
+		if err != nil {
+			return fmt.Errorf("erro ao calcular resposta: %w", err)
+		}
+
+		fmt.Println(response)
+		return nil
+	},
+}
+
+func init() {
+	ocraCmd.Flags().StringVar(&ocraChallenge, "challenge", "", "desafio (Q) recebido do servidor")
+	ocraCmd.Flags().Uint64Var(&ocraCounter, "counter", 0, "contador (C) a usar em vez do valor salvo")
+	ocraCmd.Flags().StringVar(&ocraPassword, "password", "", "senha ou PIN (P); pedida se omitida")
+	ocraCmd.Flags().StringVar(&ocraSession, "session", "", "informações de sessão (S) em hexadecimal")
+	ocraCmd.Flags().StringVar(&ocraTimestamp, "timestamp", "", "instante (T) em RFC3339 ou Unix timestamp; padrão: agora")
+	rootCmd.AddCommand(ocraCmd)
+}
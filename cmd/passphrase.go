@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/secure"
+)
+
+var newPassphraseFile string
+
+var passphraseCmd = &cobra.Command{
+	Use:   "passphrase",
+	Short: "Protege o cofre criptografado com uma passphrase",
+	Long: `Comandos para proteger a chave do cofre criptografado com uma passphrase,
+derivada com Argon2id, em vez de uma chave derivada da máquina.
+
+Com a passphrase ativa, ela é lida de --passphrase-file, da variável
+MF_PASSPHRASE ou pedida no terminal a cada uso. Apenas a chave do cofre é
+recriptografada; as contas não são alteradas.`,
+}
+
+var passphraseSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Define uma passphrase para o cofre",
+	Example: `  mf passphrase set
+  mf passphrase set --new-passphrase-file ~/.mf-passphrase`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		protected, err := secure.HasPassphrase()
+		if err != nil {
+			return fmt.Errorf("erro ao ler chave do cofre: %w", err)
+		}
+		if protected {
+			return fmt.Errorf("o cofre já tem uma passphrase; use mf passphrase change")
+		}
+
+		return setPassphrase(cmd, "Passphrase definida.")
+	},
+}
+
+var passphraseChangeCmd = &cobra.Command{
+	Use:   "change",
+	Short: "Troca a passphrase do cofre",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		protected, err := secure.HasPassphrase()
+		if err != nil {
+			return fmt.Errorf("erro ao ler chave do cofre: %w", err)
+		}
+		if !protected {
+			return fmt.Errorf("o cofre não tem passphrase; use mf passphrase set")
+		}
+
+		return setPassphrase(cmd, "Passphrase alterada.")
+	},
+}
+
+var passphraseRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "Remove a passphrase do cofre",
+	Long: `Remove a passphrase do cofre, que volta a ser protegido pela chave
+configurada (derivada da máquina, por padrão).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		protected, err := secure.HasPassphrase()
+		if err != nil {
+			return fmt.Errorf("erro ao ler chave do cofre: %w", err)
+		}
+		if !protected {
+			return fmt.Errorf("o cofre não tem passphrase")
+		}
+
+		if err := secure.RemovePassphrase(); err != nil {
+			return fmt.Errorf("erro ao remover passphrase: %w", err)
+		}
+
+		fmt.Println("Passphrase removida.")
+		return nil
+	},
+}
+
+// setPassphrase reads the new passphrase and re-wraps the vault key with it.
+// The current passphrase, if any, is read through the usual sources.
+func setPassphrase(cmd *cobra.Command, done string) error {
+	passphrase, err := readNewPassphrase(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := secure.SetPassphrase(passphrase); err != nil {
+		return fmt.Errorf("erro ao definir passphrase: %w", err)
+	}
+
+	fmt.Println(done)
+	return nil
+}
+
+func readNewPassphrase(cmd *cobra.Command) ([]byte, error) {
+	if newPassphraseFile != "" {
+		return readPassphraseFile(newPassphraseFile)
+	}
+
+	passphrase, err := promptHidden(cmd, "Nova passphrase: ")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return nil, fmt.Errorf("a passphrase não pode ser vazia")
+	}
+
+	confirmation, err := promptHidden(cmd, "Confirme a nova passphrase: ")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler passphrase: %w", err)
+	}
+	if confirmation != passphrase {
+		return nil, fmt.Errorf("as passphrases não conferem")
+	}
+
+	return []byte(passphrase), nil
+}
+
+func init() {
+	for _, c := range []*cobra.Command{passphraseSetCmd, passphraseChangeCmd} {
+		c.Flags().StringVar(&newPassphraseFile, "new-passphrase-file", "", "arquivo com a nova passphrase")
+	}
+	passphraseCmd.AddCommand(passphraseSetCmd, passphraseChangeCmd, passphraseRemoveCmd)
+	rootCmd.AddCommand(passphraseCmd)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"mf/internal/types"
+)
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+// Anything other than an explicit yes counts as no.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [s/N] ", question)
+
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "s", "sim", "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// promptHidden asks for a value without echoing it when stdin is a
+// terminal. Otherwise a single line is read, so values can be piped in.
+func promptHidden(cmd *cobra.Command, prompt string) (string, error) {
+	fmt.Fprint(cmd.ErrOrStderr(), prompt)
+
+	if in, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(in.Fd())) {
+		value, err := term.ReadPassword(int(in.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+
+	return readLine(cmd.InOrStdin())
+}
+
+// readLine reads a single line without buffering past it, so consecutive
+// prompts can read consecutive lines of piped input.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
+// ensurePIN prompts for the PIN of a PIN-based account when none is stored.
+// The PIN is only kept in memory.
+func ensurePIN(cmd *cobra.Command, account *types.Account) error {
+	if !account.NeedsPIN() || account.PIN != "" {
+		return nil
+	}
+
+	pin, err := promptHidden(cmd, fmt.Sprintf("PIN da conta '%s': ", account.Name))
+	if err != nil {
+		return fmt.Errorf("erro ao ler PIN: %w", err)
+	}
+	account.PIN = strings.TrimSpace(pin)
+	return nil
+}
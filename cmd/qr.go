@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/otpauth"
+	"mf/internal/qrcode"
+	"mf/internal/storage"
+	"mf/internal/types"
+)
+
+var (
+	qrPNG    string
+	qrSVG    string
+	qrSize   int
+	qrInvert bool
+	qrYes    bool
+)
+
+var qrCmd = &cobra.Command{
+	Use:   "qr [ACCOUNT_NAME]",
+	Short: "Exibe o QR code de uma conta para cadastrá-la em outro dispositivo",
+	Long: `Gera o QR code com a URI otpauth:// da conta especificada, para cadastrá-la
+novamente em um aplicativo autenticador.
+
+Por padrão o QR code é desenhado no terminal. Use --png ou --svg para gravar
+em um arquivo. Como o QR code revela o secret da conta, é necessário
+confirmar a operação (ou usar --yes).`,
+	Example: `  mf qr GITHUB
+  mf qr GITHUB --png github.png
+  mf qr GITHUB --svg github.svg --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		accountName := args[0]
+
+		store, err := storage.NewSecure()
+		if err != nil {
+			return fmt.Errorf("erro ao inicializar storage: %w", err)
+		}
+
+		account, err := store.LoadAccount(accountName)
+		if err != nil {
+			return fmt.Errorf("erro ao carregar conta: %w", err)
+		}
+
+		if account.NeedsPIN() || account.AccountType() == types.AccountTypeOCRA {
+			return fmt.Errorf("contas do tipo %s não podem ser exportadas como URI otpauth://", account.AccountType())
+		}
+
+		if !qrYes {
+			ok, err := confirm(cmd, fmt.Sprintf("O QR code revela o secret da conta '%s'. Continuar?", accountName))
+			if err != nil {
+				return fmt.Errorf("erro ao ler confirmação: %w", err)
+			}
+			if !ok {
+				return fmt.Errorf("operação cancelada")
+			}
+		}
+
+		if account.T0 != 0 {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Aviso: o T0 da conta não pode ser representado na URI otpauth:// e será omitido.")
+		}
+
+		uri := otpauth.Format(*account)
+
+		if qrPNG != "" {
+			if err := writeQRFile(qrPNG, func(w io.Writer) error {
+				return qrcode.WritePNG(w, uri, qrSize)
+			}); err != nil {
+				return err
+			}
+			fmt.Printf("QR code gravado em %s.\n", qrPNG)
+		}
+
+		if qrSVG != "" {
+			if err := writeQRFile(qrSVG, func(w io.Writer) error {
+				return qrcode.WriteSVG(w, uri)
+			}); err != nil {
+				return err
+			}
+			fmt.Printf("QR code gravado em %s.\n", qrSVG)
+		}
+
+		if qrPNG == "" && qrSVG == "" {
+			out, err := qrcode.RenderTerminal(uri, qrInvert)
+			if err != nil {
+				return fmt.Errorf("erro ao gerar QR code: %w", err)
+			}
+			fmt.Print(out)
+		}
+
+		return nil
+	},
+}
+
+func writeQRFile(path string, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo: %w", err)
+	}
+
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("erro ao gerar QR code: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("erro ao gravar arquivo: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	qrCmd.Flags().StringVar(&qrPNG, "png", "", "grava o QR code como imagem PNG no arquivo informado")
+	qrCmd.Flags().StringVar(&qrSVG, "svg", "", "grava o QR code como SVG no arquivo informado")
+	qrCmd.Flags().IntVar(&qrSize, "size", 512, "tamanho da imagem PNG em pixels")
+	qrCmd.Flags().BoolVar(&qrInvert, "invert", false, "inverte as cores no terminal (para temas claros)")
+	qrCmd.Flags().BoolVarP(&qrYes, "yes", "y", false, "não pede confirmação antes de revelar o secret")
+	rootCmd.AddCommand(qrCmd)
+}
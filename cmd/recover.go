@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/secure"
+)
+
+var (
+	recoverKey         string
+	recoverOldHostname string
+	recoverShowKey     bool
+	recoverAdopt       bool
+	recoverYes         bool
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover",
+	Short: "Recupera o acesso ao cofre criptografado",
+	Long: `Recupera o acesso ao cofre quando a chave derivada da máquina deixou de
+funcionar, por exemplo após reinstalar o sistema, ou quando a passphrase foi
+esquecida.
+
+A chave do cofre é desbloqueada com a chave de recuperação exibida na
+primeira execução, ou com a identidade que esta máquina tinha sob um nome de
+host anterior (--old-hostname), e protegida novamente pela chave configurada.
+Uma passphrase existente é removida e pode ser definida de novo com
+mf passphrase set.
+
+Sem --recovery-key nem --old-hostname, a chave de recuperação é pedida no
+terminal.
+
+Com --adopt, passa a proteger com o --key-file, MF_KEY_FILE, key_command ou
+keyring configurado um cofre ainda protegido pela identidade desta máquina.
+A troca nunca é feita automaticamente, para que um MF_KEY_FILE esquecido não
+bloqueie o cofre.
+
+Com --show-key, exibe a chave de recuperação do cofre atual. Como ela abre o
+cofre, é necessário confirmar a operação (ou usar --yes).`,
+	Example: `  mf recover
+  mf recover --recovery-key ABCD-EFGH-...
+  mf recover --old-hostname notebook-antigo
+  mf recover --adopt --key-file ~/mf.key
+  mf recover --show-key
+  mf recover --show-key --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if recoverAdopt {
+			if recoverShowKey || recoverKey != "" || recoverOldHostname != "" {
+				return fmt.Errorf("--adopt não pode ser usado com --show-key, --recovery-key ou --old-hostname")
+			}
+			if err := secure.AdoptKeySource(); err != nil {
+				return fmt.Errorf("erro ao trocar a chave do cofre: %w", err)
+			}
+			fmt.Println("Cofre protegido pela chave configurada.")
+			return nil
+		}
+
+		if recoverShowKey {
+			if recoverKey != "" || recoverOldHostname != "" {
+				return fmt.Errorf("--show-key não pode ser usado com --recovery-key ou --old-hostname")
+			}
+			if !recoverYes {
+				ok, err := confirm(cmd, "A chave de recuperação abre o cofre sem a chave da máquina nem a passphrase. Continuar?")
+				if err != nil {
+					return fmt.Errorf("erro ao ler confirmação: %w", err)
+				}
+				if !ok {
+					return fmt.Errorf("operação cancelada")
+				}
+			}
+			recoveryKey, err := secure.RecoveryKey()
+			if err != nil {
+				return fmt.Errorf("erro ao ler chave do cofre: %w", err)
+			}
+			fmt.Println(recoveryKey)
+			return nil
+		}
+
+		if recoverKey != "" && recoverOldHostname != "" {
+			return fmt.Errorf("use apenas um de --recovery-key e --old-hostname")
+		}
+
+		var err error
+		if recoverOldHostname != "" {
+			err = secure.RecoverWithHostname(recoverOldHostname)
+		} else {
+			if recoverKey == "" {
+				if recoverKey, err = promptHidden(cmd, "Chave de recuperação: "); err != nil {
+					return fmt.Errorf("erro ao ler chave de recuperação: %w", err)
+				}
+			}
+			err = secure.RecoverWithKey(recoverKey)
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao recuperar cofre: %w", err)
+		}
+
+		fmt.Println("Cofre recuperado.")
+		return nil
+	},
+}
+
+func init() {
+	recoverCmd.Flags().StringVar(&recoverKey, "recovery-key", "", "chave de recuperação exibida na primeira execução")
+	recoverCmd.Flags().StringVar(&recoverOldHostname, "old-hostname", "", "nome de host anterior desta máquina")
+	recoverCmd.Flags().BoolVar(&recoverAdopt, "adopt", false, "protege o cofre com a chave externa ou do keyring configurada")
+	recoverCmd.Flags().BoolVar(&recoverShowKey, "show-key", false, "exibe a chave de recuperação do cofre atual")
+	recoverCmd.Flags().BoolVarP(&recoverYes, "yes", "y", false, "não pede confirmação antes de exibir a chave de recuperação")
+	rootCmd.AddCommand(recoverCmd)
+}
@@ -2,13 +2,21 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"mf/internal/secure"
 )
 
 var (
 	appVersion   = "dev"
 	appBuildTime = "unknown"
+
+	passphraseFile string
+	keyFile        string
 )
 
 var rootCmd = &cobra.Command{
@@ -36,6 +44,71 @@ func SetVersion(version, buildTime string) {
 	rootCmd.SetVersionTemplate(fmt.Sprintf("MF version %s (built %s)\n", version, buildTime))
 }
 
+// readVaultPassphrase supplies the vault passphrase from --passphrase-file,
+// MF_PASSPHRASE or, on a terminal, a hidden prompt.
+func readVaultPassphrase() ([]byte, error) {
+	if passphraseFile != "" {
+		return readPassphraseFile(passphraseFile)
+	}
+	if passphrase := os.Getenv(secure.PassphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, fmt.Errorf("%w: use %s ou --passphrase-file", secure.ErrPassphraseRequired, secure.PassphraseEnv)
+	}
+
+	passphrase, err := promptHidden(rootCmd, "Passphrase do cofre: ")
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler passphrase: %w", err)
+	}
+	return []byte(passphrase), nil
+}
+
+// showRecoveryKey prints the recovery key of a newly created vault to stderr,
+// so it does not end up in scripts that capture the codes. When stderr is not
+// a terminal it may still be logged, as on CI runners or cron jobs, so only a
+// notice is printed there.
+func showRecoveryKey(recoveryKey string, hostnameIdentity bool) {
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		fmt.Fprintln(os.Stderr, "Cofre criado. Execute mf recover --show-key em um terminal para ver a chave de recuperação.")
+	} else {
+		fmt.Fprintf(os.Stderr, `Chave de recuperação do cofre:
+
+    %s
+
+Guarde-a em local seguro. Ela permite recuperar as contas com mf recover se
+a identidade desta máquina mudar ou a passphrase for esquecida.
+Ela pode ser exibida novamente com mf recover --show-key.
+
+`, recoveryKey)
+	}
+
+	if hostnameIdentity {
+		fmt.Fprint(os.Stderr, `Aviso: este sistema não tem machine ID (/etc/machine-id), então o cofre
+está vinculado ao nome do host. Se ele mudar, como em containers reiniciados,
+o cofre só poderá ser aberto com mf recover. Monte /etc/machine-id no
+container ou use --key-file, MF_KEY_FILE ou key_command.
+
+`)
+	}
+}
+
+// readPassphraseFile reads a passphrase from a file, ignoring a trailing
+// newline.
+func readPassphraseFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler arquivo de passphrase: %w", err)
+	}
+	return []byte(strings.TrimRight(string(data), "\r\n")), nil
+}
+
 func init() {
-	cobra.OnInitialize()
+	cobra.OnInitialize(func() {
+		secure.KeyFile = keyFile
+	})
+	rootCmd.PersistentFlags().StringVar(&passphraseFile, "passphrase-file", "", "arquivo com a passphrase do cofre (alternativa a MF_PASSPHRASE)")
+	rootCmd.PersistentFlags().StringVar(&keyFile, "key-file", "", "arquivo com a chave que protege o cofre (alternativa a MF_KEY_FILE)")
+	secure.PassphraseSource = readVaultPassphrase
+	secure.RecoveryKeyIssued = showRecoveryKey
 }
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/config"
+	"mf/internal/storage"
+	"mf/internal/timesync"
+	"mf/internal/types"
+)
+
+// driftWarning is the offset above which codes start to fail with the usual
+// one-step tolerance of TOTP servers.
+const driftWarning = 5 * time.Second
+
+var (
+	timeServer  string
+	timeHTTP    string
+	timeTimeout time.Duration
+	timeSave    bool
+	timeAccount string
+)
+
+var timeCmd = &cobra.Command{
+	Use:   "time",
+	Short: "Verifica e corrige a diferença do relógio local",
+	Long: `Comandos para detectar e compensar a diferença entre o relógio da máquina
+e a hora correta. Tokens TOTP dependem do horário e falham silenciosamente
+quando o relógio está adiantado ou atrasado.
+
+A correção pode ser global ou por conta; a correção da conta é somada à
+global.`,
+}
+
+var timeCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Mede a diferença do relógio contra um servidor de hora",
+	Long: `Mede a diferença entre o relógio local e um servidor SNTP (por padrão o
+configurado ou pool.ntp.org) ou o cabeçalho Date de um servidor HTTP.
+
+Com --save a diferença medida é gravada como correção global, ou da conta
+informada em --account.`,
+	Example: `  mf time check
+  mf time check --server time.google.com --save
+  mf time check --http https://api.example.com --account EXAMPLE --save`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("erro ao carregar configuração: %w", err)
+		}
+
+		var result *timesync.Result
+		if timeHTTP != "" {
+			result, err = timesync.QueryHTTP(timeHTTP, timeTimeout)
+		} else {
+			server := timeServer
+			if server == "" {
+				server = cfg.TimeServer
+			}
+			if server == "" {
+				server = timesync.DefaultServer
+			}
+			result, err = timesync.QuerySNTP(server, timeTimeout)
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao consultar servidor de hora: %w", err)
+		}
+
+		fmt.Printf("Servidor:      %s\n", result.Source)
+		fmt.Printf("Diferença:     %s (ida e volta %s)\n", formatOffset(result.Offset), result.RoundTrip.Round(time.Millisecond))
+		fmt.Printf("Correção atual: %s\n", formatOffset(cfg.TimeOffset()))
+
+		if !timeSave {
+			if abs(result.Offset-cfg.TimeOffset()) >= driftWarning {
+				fmt.Println("Atenção: o relógio está fora de sincronia e tokens TOTP podem ser rejeitados. Use --save para corrigir.")
+			}
+			return nil
+		}
+
+		if timeAccount != "" {
+			// The account offset is applied on top of the global one.
+			return saveAccountOffset(timeAccount, result.Offset-cfg.TimeOffset())
+		}
+
+		cfg.SetTimeOffset(result.Offset)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("erro ao salvar configuração: %w", err)
+		}
+		fmt.Printf("Correção global definida para %s.\n", formatOffset(cfg.TimeOffset()))
+		return nil
+	},
+}
+
+var timeOffsetCmd = &cobra.Command{
+	Use:   "offset [DURATION]",
+	Short: "Mostra ou define manualmente a correção do relógio",
+	Long: `Sem argumentos mostra a correção atual. Com DURATION (ex.: 2s, -1.5s, 0)
+define a correção global, ou da conta informada em --account.`,
+	Example: `  mf time offset
+  mf time offset -- -3s
+  mf time offset 2s --account EXAMPLE`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("erro ao carregar configuração: %w", err)
+		}
+
+		if len(args) == 0 {
+			fmt.Printf("Correção global: %s\n", formatOffset(cfg.TimeOffset()))
+			if timeAccount != "" {
+				store, err := storage.NewSecure()
+				if err != nil {
+					return fmt.Errorf("erro ao inicializar storage: %w", err)
+				}
+				account, err := store.LoadAccount(timeAccount)
+				if err != nil {
+					return fmt.Errorf("erro ao carregar conta: %w", err)
+				}
+				fmt.Printf("Correção da conta '%s': %s\n", timeAccount, formatOffset(account.TimeOffset()))
+			}
+			return nil
+		}
+
+		offset, err := time.ParseDuration(args[0])
+		if err != nil {
+			return fmt.Errorf("duração inválida '%s': %w", args[0], err)
+		}
+
+		if timeAccount != "" {
+			return saveAccountOffset(timeAccount, offset)
+		}
+
+		cfg.SetTimeOffset(offset)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("erro ao salvar configuração: %w", err)
+		}
+		fmt.Printf("Correção global definida para %s.\n", formatOffset(cfg.TimeOffset()))
+		return nil
+	},
+}
+
+func saveAccountOffset(accountName string, offset time.Duration) error {
+	store, err := storage.NewSecure()
+	if err != nil {
+		return fmt.Errorf("erro ao inicializar storage: %w", err)
+	}
+
+	account, err := store.UpdateAccount(accountName, func(account *types.Account) error {
+		account.TimeOffsetMillis = offset.Round(time.Millisecond).Milliseconds()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao atualizar conta: %w", err)
+	}
+
+	fmt.Printf("Correção da conta '%s' definida para %s.\n", accountName, formatOffset(account.TimeOffset()))
+	return nil
+}
+
+func formatOffset(offset time.Duration) string {
+	rounded := offset.Round(time.Millisecond)
+	if rounded >= 0 {
+		return "+" + rounded.String()
+	}
+	return rounded.String()
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func init() {
+	timeCheckCmd.Flags().StringVar(&timeServer, "server", "", "servidor SNTP (padrão: configurado ou "+timesync.DefaultServer+")")
+	timeCheckCmd.Flags().StringVar(&timeHTTP, "http", "", "usa o cabeçalho Date desta URL em vez de SNTP")
+	timeCheckCmd.Flags().DurationVar(&timeTimeout, "timeout", timesync.DefaultTimeout, "tempo máximo de espera pela resposta")
+	timeCheckCmd.Flags().BoolVar(&timeSave, "save", false, "grava a diferença medida como correção")
+	timeCheckCmd.Flags().StringVar(&timeAccount, "account", "", "aplica a correção apenas a esta conta")
+	timeOffsetCmd.Flags().StringVar(&timeAccount, "account", "", "mostra ou define a correção desta conta")
+
+	timeCmd.AddCommand(timeCheckCmd)
+	timeCmd.AddCommand(timeOffsetCmd)
+	rootCmd.AddCommand(timeCmd)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"mf/internal/config"
+	"mf/internal/fsutil"
+	"mf/internal/replay"
+	"mf/internal/storage"
+	"mf/internal/totp"
+	"mf/internal/types"
+)
+
+const replayCacheFile = "used-codes.json"
+
+var (
+	verifySecret    string
+	verifyType      string
+	verifyAlgorithm string
+	verifyDigits    int
+	verifyPeriod    int
+	verifyWindow    int
+	verifyReplay    bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [ACCOUNT_NAME] CODE",
+	Short: "Verifica se um código é válido para uma conta ou secret",
+	Long: `Verifica se um código é válido para a conta especificada, aceitando até
+--window passos antes ou depois do atual, e informa qual passo coincidiu.
+
+Com --secret a verificação é feita diretamente contra o secret informado,
+sem usar uma conta salva. Com --replay os códigos aceitos são registrados
+localmente e um segundo uso do mesmo código é rejeitado.
+
+O comando termina com código de saída diferente de zero se o código não for
+válido.`,
+	Example: `  mf verify GITHUB 123456
+  mf verify --secret JBSWY3DPEHPK3PXP 123456 --window 2
+  mf verify GITHUB 123456 --replay`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		account, err := verifyAccount(args)
+		if err != nil {
+			return err
+		}
+		code := args[len(args)-1]
+
+		if err := validateAccount(account); err != nil {
+			return err
+		}
+		if account.AccountType() == types.AccountTypeOCRA {
+			return fmt.Errorf("verificação de respostas OCRA não é suportada")
+		}
+
+		cmd.SilenceUsage = true
+
+		if err := ensurePIN(cmd, account); err != nil {
+			return err
+		}
+
+		clock, err := accountClock(account)
+		if err != nil {
+			return err
+		}
+		now := clock.Now()
+
+		var offset int
+		var ok bool
+		var step int64
+		var stepDuration time.Duration
+
+		switch account.AccountType() {
+		case types.AccountTypeHOTP:
+			offset, ok, err = totp.VerifyHOTP(account.Secret, code, account.Counter, verifyWindow, tokenParams(account))
+			step = int64(account.Counter) + int64(offset)
+		default:
+			period := accountPeriod(account)
+			offset, ok, err = totp.Verify(code, now, period, verifyWindow, func(t time.Time) (string, error) {
+				return generateCode(account, t)
+			})
+			step = (now.Unix()-account.T0)/int64(period) + int64(offset)
+			stepDuration = time.Duration(period) * time.Second
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao verificar código: %w", err)
+		}
+
+		if !ok {
+			return fmt.Errorf("código inválido")
+		}
+
+		if verifyReplay {
+			if err := checkReplay(account, step, stepDuration, now); err != nil {
+				return err
+			}
+		}
+
+		switch account.AccountType() {
+		case types.AccountTypeHOTP:
+			fmt.Printf("Código válido (contador %d, deslocamento %+d).\n", step, offset)
+		default:
+			fmt.Printf("Código válido (passo %+d).\n", offset)
+		}
+		return nil
+	},
+}
+
+func verifyAccount(args []string) (*types.Account, error) {
+	if verifySecret != "" {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("com --secret informe apenas o código")
+		}
+		return &types.Account{
+			Name:      "secret",
+			Secret:    verifySecret,
+			Type:      strings.ToLower(verifyType),
+			Algorithm: strings.ToUpper(verifyAlgorithm),
+			Digits:    verifyDigits,
+			Period:    verifyPeriod,
+		}, nil
+	}
+
+	if len(args) != 2 {
+		return nil, fmt.Errorf("informe o nome da conta e o código")
+	}
+
+	store, err := storage.NewSecure()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao inicializar storage: %w", err)
+	}
+
+	account, err := store.LoadAccount(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("erro ao carregar conta: %w", err)
+	}
+	return account, nil
+}
+
+// checkReplay rejects a code whose step was already accepted and records it
+// otherwise. TOTP entries are kept while the step can still fall inside the
+// window; HOTP counters never become valid again, so they are kept longer.
+func checkReplay(account *types.Account, step int64, stepDuration time.Duration, now time.Time) error {
+	configDir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+
+	// Hold the lock from reading the cache until it is saved, so parallel
+	// verifications cannot both accept the same code.
+	lock, err := fsutil.LockDir(configDir)
+	if err != nil {
+		return fmt.Errorf("erro ao bloquear cache de códigos: %w", err)
+	}
+	defer lock.Unlock()
+
+	cache, err := replay.Open(filepath.Join(configDir, replayCacheFile))
+	if err != nil {
+		return fmt.Errorf("erro ao abrir cache de códigos: %w", err)
+	}
+
+	key := replay.Key(account.AccountType(), account.Secret, strconv.FormatInt(step, 10))
+	if cache.Seen(key, now) {
+		return fmt.Errorf("código já utilizado (replay)")
+	}
+
+	ttl := 30 * 24 * time.Hour
+	if stepDuration > 0 {
+		ttl = time.Duration(2*verifyWindow+2) * stepDuration
+	}
+	cache.Add(key, now.Add(ttl))
+
+	if err := cache.Save(now); err != nil {
+		return fmt.Errorf("erro ao salvar cache de códigos: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifySecret, "secret", "", "verifica contra este secret em vez de uma conta salva")
+	verifyCmd.Flags().StringVar(&verifyType, "type", types.AccountTypeTOTP, "tipo do secret informado com --secret (totp, hotp, steam, motp ou yandex)")
+	verifyCmd.Flags().StringVar(&verifyAlgorithm, "algorithm", totp.DefaultAlgorithm, "algoritmo HMAC do secret informado com --secret")
+	verifyCmd.Flags().IntVar(&verifyDigits, "digits", totp.DefaultDigits, "número de dígitos do secret informado com --secret")
+	verifyCmd.Flags().IntVar(&verifyPeriod, "period", totp.DefaultPeriod, "período em segundos do secret informado com --secret")
+	verifyCmd.Flags().IntVarP(&verifyWindow, "window", "w", 1, "número de passos aceitos antes e depois do atual")
+	verifyCmd.Flags().BoolVar(&verifyReplay, "replay", false, "rejeita códigos já aceitos anteriormente (cache local)")
+	rootCmd.AddCommand(verifyCmd)
+}
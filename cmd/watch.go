@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"mf/internal/storage"
+	"mf/internal/totp"
+	"mf/internal/types"
+)
+
+const (
+	watchRefresh  = 250 * time.Millisecond
+	watchBarWidth = 20
+
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiClearLine  = "\x1b[2K"
+	ansiReset      = "\x1b[0m"
+	ansiBold       = "\x1b[1m"
+	ansiRed        = "\x1b[31m"
+	ansiDim        = "\x1b[2m"
+)
+
+var watchWarn time.Duration
+
+// watchEntry is an account shown by mf watch together with its clock, so the
+// configuration is read only once.
+type watchEntry struct {
+	account *types.Account
+	clock   totp.Clock
+	period  int
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [ACCOUNT_NAME...]",
+	Short: "Mostra os códigos atuais e os atualiza continuamente",
+	Long: `Mostra os códigos atuais das contas informadas (ou de todas) e os redesenha
+no lugar à medida que expiram.
+
+Cada conta tem uma barra com o tempo restante do seu período; códigos que
+expiram em menos de --warn são destacados. Contas HOTP e OCRA são ignoradas,
+pois gerar seus códigos consome o contador. Encerre com Ctrl-C.
+
+Se a saída não for um terminal, os códigos são impressos uma única vez.`,
+	Example: `  mf watch
+  mf watch GITHUB AWS-DEV
+  mf watch --warn 10s`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := loadWatchEntries(cmd, args)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("Nenhuma conta baseada em tempo encontrada.")
+			return nil
+		}
+
+		out := cmd.OutOrStdout()
+		if f, ok := out.(*os.File); !ok || !term.IsTerminal(int(f.Fd())) {
+			renderWatch(out, entries, false)
+			return nil
+		}
+
+		return runWatch(out, entries)
+	},
+}
+
+func loadWatchEntries(cmd *cobra.Command, names []string) ([]watchEntry, error) {
+	store, err := storage.NewSecure()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao inicializar storage: %w", err)
+	}
+
+	if len(names) == 0 {
+		names, err = store.ListAccounts()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao listar contas: %w", err)
+		}
+	}
+
+	var entries []watchEntry
+	for _, name := range names {
+		account, err := store.LoadAccount(name)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar conta '%s': %w", name, err)
+		}
+
+		switch account.AccountType() {
+		case types.AccountTypeHOTP, types.AccountTypeOCRA:
+			continue
+		}
+
+		if err := ensurePIN(cmd, account); err != nil {
+			return nil, err
+		}
+
+		clock, err := accountClock(account)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, watchEntry{
+			account: account,
+			clock:   clock,
+			period:  accountPeriod(account),
+		})
+	}
+
+	return entries, nil
+}
+
+// runWatch redraws the codes until interrupted. The cursor is hidden while
+// running and restored on exit.
+func runWatch(out io.Writer, entries []watchEntry) error {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	fmt.Fprint(out, ansiHideCursor)
+	defer fmt.Fprint(out, ansiReset+ansiShowCursor)
+
+	ticker := time.NewTicker(watchRefresh)
+	defer ticker.Stop()
+
+	renderWatch(out, entries, true)
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+			// Move back to the first line and draw over the previous frame.
+			fmt.Fprintf(out, "\x1b[%dA", len(entries))
+			renderWatch(out, entries, true)
+		}
+	}
+}
+
+func renderWatch(out io.Writer, entries []watchEntry, color bool) {
+	nameWidth := 0
+	for _, entry := range entries {
+		nameWidth = max(nameWidth, len(entry.account.Name))
+	}
+
+	for _, entry := range entries {
+		line := watchLine(entry, nameWidth, color)
+		if color {
+			line = ansiClearLine + line
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+func watchLine(entry watchEntry, nameWidth int, color bool) string {
+	name := fmt.Sprintf("%-*s", nameWidth, entry.account.Name)
+
+	now := entry.clock.Now()
+	code, err := generateCode(entry.account, now)
+	if err != nil {
+		return fmt.Sprintf("%s  erro: %v", name, err)
+	}
+
+	remaining := totp.Remaining(now, entry.period, entry.account.T0)
+	seconds := int(math.Ceil(remaining.Seconds()))
+
+	filled := int(math.Round(remaining.Seconds() / float64(entry.period) * watchBarWidth))
+	filled = min(max(filled, 0), watchBarWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", watchBarWidth-filled)
+
+	if !color {
+		return fmt.Sprintf("%s  %s  %s %3ds", name, code, bar, seconds)
+	}
+
+	style := ansiBold
+	if remaining <= watchWarn {
+		style = ansiBold + ansiRed
+	}
+	return fmt.Sprintf("%s  %s%s%s  %s%s%s %3ds", name, style, code, ansiReset, ansiDim, bar, ansiReset, seconds)
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchWarn, "warn", 5*time.Second, "destaca códigos que expiram em menos que este tempo")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mf/internal/fsutil"
+)
+
+const configFile = "config.json"
+
+// Config holds installation-wide settings stored in config.json inside the
+// configuration directory.
+type Config struct {
+	// TimeOffsetMillis is added to the system clock before generating codes.
+	TimeOffsetMillis int64 `json:"time_offset_ms,omitempty"`
+	// TimeServer is the SNTP server used by mf time check.
+	TimeServer string `json:"time_server,omitempty"`
+	// KeySource selects where the key protecting the vault key comes from:
+	// "machine" (default) or "keyring".
+	KeySource string `json:"key_source,omitempty"`
+	// KeyCommand, when set, is run through the shell and its output is used
+	// as the key protecting the vault key, overriding KeySource.
+	KeyCommand string `json:"key_command,omitempty"`
+}
+
+// Dir returns the mf configuration directory (~/.config/mf), creating it with
+// owner-only permissions if needed.
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	configDir := filepath.Join(homeDir, ".config", "mf")
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	return configDir, nil
+}
+
+// Load reads config.json. A missing file yields the default configuration.
+func Load() (*Config, error) {
+	configDir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	data, err := os.ReadFile(filepath.Join(configDir, configFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes the configuration back to config.json.
+func (c *Config) Save() error {
+	configDir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(filepath.Join(configDir, configFile), data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// TimeOffset returns the global clock offset.
+func (c *Config) TimeOffset() time.Duration {
+	return time.Duration(c.TimeOffsetMillis) * time.Millisecond
+}
+
+// SetTimeOffset sets the global clock offset, rounded to milliseconds.
+func (c *Config) SetTimeOffset(offset time.Duration) {
+	c.TimeOffsetMillis = offset.Round(time.Millisecond).Milliseconds()
+}
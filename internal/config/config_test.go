@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadMissingConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.TimeOffset() != 0 || cfg.TimeServer != "" {
+		t.Errorf("Expected default config, got %+v", cfg)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg := &Config{TimeServer: "ntp.example.com"}
+	cfg.SetTimeOffset(-1500 * time.Millisecond)
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if loaded.TimeOffset() != -1500*time.Millisecond {
+		t.Errorf("Expected offset -1.5s, got %v", loaded.TimeOffset())
+	}
+	if loaded.TimeServer != "ntp.example.com" {
+		t.Errorf("Expected time server ntp.example.com, got %s", loaded.TimeServer)
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".config", "mf", configFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestLoadInvalidConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir, err := Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, configFile), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for invalid config file")
+	}
+}
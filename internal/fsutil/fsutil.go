@@ -0,0 +1,75 @@
+// Package fsutil provides crash-safe file writes and an advisory lock that
+// serialises read-modify-write sequences between mf processes.
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile is the file LockDir locks inside the directory.
+const LockFile = ".lock"
+
+// WriteFileAtomic writes data to a temporary file in the same directory,
+// flushes it to disk and renames it over path, so readers and a crash never
+// leave a partially written file behind.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Lock is an exclusive advisory lock held on a directory.
+type Lock struct {
+	file *os.File
+}
+
+// LockDir blocks until it holds the exclusive lock on dir. The lock is not
+// reentrant: locking the same directory again before Unlock deadlocks, even
+// within one process.
+func LockDir(dir string) (*Lock, error) {
+	file, err := os.OpenFile(filepath.Join(dir, LockFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", dir, err)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
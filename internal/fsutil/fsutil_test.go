@@ -0,0 +1,93 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vault.enc")
+
+	if err := WriteFileAtomic(path, []byte("first"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic failed: %v", err)
+	}
+	if err := WriteFileAtomic(path, []byte("second"), 0600); err != nil {
+		t.Fatalf("WriteFileAtomic over existing file failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data) != "second" {
+		t.Errorf("Expected %q, got %q", "second", data)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("Expected permissions 0600, got %o", perm)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
+
+func TestWriteFileAtomicMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "vault.enc")
+	if err := WriteFileAtomic(path, []byte("data"), 0600); err == nil {
+		t.Error("Expected an error when the directory does not exist")
+	}
+}
+
+func TestLockDirIsExclusive(t *testing.T) {
+	dir := t.TempDir()
+
+	lock, err := LockDir(dir)
+	if err != nil {
+		t.Fatalf("LockDir failed: %v", err)
+	}
+
+	acquired := make(chan *Lock)
+	go func() {
+		second, err := LockDir(dir)
+		if err != nil {
+			t.Errorf("Second LockDir failed: %v", err)
+			close(acquired)
+			return
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Second lock acquired while the first was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+
+	select {
+	case second := <-acquired:
+		if second != nil {
+			second.Unlock()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Second lock not acquired after the first was released")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build windows
+
+package fsutil
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRange is the number of bytes locked. Every process locks the same first
+// byte, which is enough for mutual exclusion.
+const lockRange = 1
+
+func lockFile(file *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, lockRange, 0, &overlapped)
+}
+
+func unlockFile(file *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, lockRange, 0, &overlapped)
+}
+
+// syncDir is a no-op: directories cannot be opened for flushing on Windows.
+func syncDir(string) error {
+	return nil
+}
@@ -0,0 +1,305 @@
+// Package migration decodes the otpauth-migration:// export format used by
+// Google Authenticator's "Transfer accounts" feature.
+//
+// The data parameter holds a base64 encoded MigrationPayload protobuf:
+//
+//	message MigrationPayload {
+//	  repeated OtpParameters otp_parameters = 1;
+//	  int32 version = 2;
+//	  int32 batch_size = 3;
+//	  int32 batch_index = 4;
+//	  int32 batch_id = 5;
+//	}
+//
+//	message OtpParameters {
+//	  bytes secret = 1;
+//	  string name = 2;
+//	  string issuer = 3;
+//	  Algorithm algorithm = 4;   // 1 SHA1, 2 SHA256, 3 SHA512, 4 MD5
+//	  DigitCount digits = 5;     // 1 six, 2 eight
+//	  OtpType type = 6;          // 1 HOTP, 2 TOTP
+//	  int64 counter = 7;
+//	}
+//
+// The format is small enough that it is decoded by hand instead of pulling
+// in a protobuf runtime.
+package migration
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"mf/internal/otpauth"
+	"mf/internal/types"
+)
+
+const Scheme = "otpauth-migration"
+
+type Payload struct {
+	Accounts   []types.Account
+	Version    int
+	BatchSize  int
+	BatchIndex int
+	BatchID    int
+}
+
+// IsURI reports whether s looks like an otpauth-migration:// URI.
+func IsURI(s string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(s)), Scheme+"://")
+}
+
+// Decode parses a single otpauth-migration:// URI.
+func Decode(uri string) (*Payload, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration URI: %w", err)
+	}
+
+	if !strings.EqualFold(u.Scheme, Scheme) {
+		return nil, fmt.Errorf("invalid migration URI: unexpected scheme '%s'", u.Scheme)
+	}
+
+	data := u.Query().Get("data")
+	if data == "" {
+		return nil, fmt.Errorf("invalid migration URI: missing data")
+	}
+
+	raw, err := decodeBase64(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid migration URI: %w", err)
+	}
+
+	return decodePayload(raw)
+}
+
+// DecodeAll decodes every URI of a (possibly multi-batch) export and returns
+// the accounts in batch order. It fails if a batch of an export is missing or
+// repeated, so a partial transfer is never silently imported.
+func DecodeAll(uris []string) ([]types.Account, error) {
+	type batchKey struct{ id, index int }
+
+	batches := make(map[batchKey]*Payload)
+	sizes := make(map[int]int)
+
+	for _, uri := range uris {
+		payload, err := Decode(uri)
+		if err != nil {
+			return nil, err
+		}
+
+		key := batchKey{payload.BatchID, payload.BatchIndex}
+		if _, exists := batches[key]; exists {
+			return nil, fmt.Errorf("batch %d of export %d was provided more than once", payload.BatchIndex+1, payload.BatchID)
+		}
+		batches[key] = payload
+
+		if size, ok := sizes[payload.BatchID]; ok && size != payload.BatchSize {
+			return nil, fmt.Errorf("inconsistent batch size for export %d", payload.BatchID)
+		}
+		sizes[payload.BatchID] = payload.BatchSize
+	}
+
+	keys := make([]batchKey, 0, len(batches))
+	for key := range batches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].id != keys[j].id {
+			return keys[i].id < keys[j].id
+		}
+		return keys[i].index < keys[j].index
+	})
+
+	for id, size := range sizes {
+		for index := 0; index < size; index++ {
+			if _, ok := batches[batchKey{id, index}]; !ok {
+				return nil, fmt.Errorf("missing batch %d of %d for export %d", index+1, size, id)
+			}
+		}
+	}
+
+	var accounts []types.Account
+	for _, key := range keys {
+		accounts = append(accounts, batches[key].Accounts...)
+	}
+	return accounts, nil
+}
+
+func decodeBase64(data string) ([]byte, error) {
+	// A '+' that was not percent-encoded turns into a space when the query
+	// string is decoded.
+	data = strings.ReplaceAll(data, " ", "+")
+
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	}
+	for _, encoding := range encodings {
+		if raw, err := encoding.DecodeString(data); err == nil {
+			return raw, nil
+		}
+	}
+	return nil, fmt.Errorf("data is not valid base64")
+}
+
+func decodePayload(raw []byte) (*Payload, error) {
+	payload := &Payload{BatchSize: 1}
+	r := &reader{data: raw}
+
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration payload: %w", err)
+		}
+
+		switch {
+		case field == 1 && wireType == wireBytes:
+			message, err := r.bytes()
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration payload: %w", err)
+			}
+			account, err := decodeOtpParameters(message)
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration payload: %w", err)
+			}
+			payload.Accounts = append(payload.Accounts, *account)
+		case field >= 2 && field <= 5 && wireType == wireVarint:
+			value, err := r.varint()
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration payload: %w", err)
+			}
+			switch field {
+			case 2:
+				payload.Version = int(value)
+			case 3:
+				payload.BatchSize = int(value)
+			case 4:
+				payload.BatchIndex = int(value)
+			case 5:
+				payload.BatchID = int(int32(value))
+			}
+		default:
+			if err := r.skip(wireType); err != nil {
+				return nil, fmt.Errorf("invalid migration payload: %w", err)
+			}
+		}
+	}
+
+	if payload.BatchSize < 1 || payload.BatchIndex < 0 || payload.BatchIndex >= payload.BatchSize {
+		return nil, fmt.Errorf("invalid migration payload: batch %d of %d", payload.BatchIndex+1, payload.BatchSize)
+	}
+
+	return payload, nil
+}
+
+func decodeOtpParameters(raw []byte) (*types.Account, error) {
+	var (
+		secret      []byte
+		name        string
+		issuer      string
+		algorithm   uint64
+		digits      uint64
+		otpType     uint64
+		counter     uint64
+		hasAnyField bool
+	)
+
+	r := &reader{data: raw}
+	for !r.done() {
+		field, wireType, err := r.tag()
+		if err != nil {
+			return nil, err
+		}
+		hasAnyField = true
+
+		switch {
+		case field >= 1 && field <= 3 && wireType == wireBytes:
+			value, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			switch field {
+			case 1:
+				secret = value
+			case 2:
+				name = string(value)
+			case 3:
+				issuer = string(value)
+			}
+		case field >= 4 && field <= 7 && wireType == wireVarint:
+			value, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			switch field {
+			case 4:
+				algorithm = value
+			case 5:
+				digits = value
+			case 6:
+				otpType = value
+			case 7:
+				counter = value
+			}
+		default:
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !hasAnyField || len(secret) == 0 {
+		return nil, fmt.Errorf("account without secret")
+	}
+
+	account := &types.Account{
+		Issuer: issuer,
+		Secret: base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret),
+	}
+
+	// The name is usually "Issuer:label", the same as an otpauth label.
+	account.Name = otpauth.AccountName(issuer, name)
+	if account.Name == "" {
+		return nil, fmt.Errorf("account without name")
+	}
+
+	switch algorithm {
+	case 0, 1:
+		account.Algorithm = "SHA1"
+	case 2:
+		account.Algorithm = "SHA256"
+	case 3:
+		account.Algorithm = "SHA512"
+	case 4:
+		account.Algorithm = "MD5"
+	default:
+		return nil, fmt.Errorf("account '%s': unknown algorithm %d", account.Name, algorithm)
+	}
+
+	switch digits {
+	case 0, 1:
+		account.Digits = 6
+	case 2:
+		account.Digits = 8
+	default:
+		return nil, fmt.Errorf("account '%s': unknown digit count %d", account.Name, digits)
+	}
+
+	switch otpType {
+	case 0, 2:
+		account.Type = types.AccountTypeTOTP
+	case 1:
+		account.Type = types.AccountTypeHOTP
+		account.Counter = counter
+	default:
+		return nil, fmt.Errorf("account '%s': unknown OTP type %d", account.Name, otpType)
+	}
+
+	return account, nil
+}
@@ -0,0 +1,172 @@
+package migration
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+
+	"mf/internal/types"
+)
+
+// Sample export containing a single TOTP account "Example:alice@google.com"
+// with the secret "Hello!\xde\xad\xbe\xef".
+const sampleURI = "otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZTAC"
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = appendVarint(b, uint64(field<<3|wireBytes))
+	b = appendVarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	b = appendVarint(b, uint64(field<<3|wireVarint))
+	return appendVarint(b, value)
+}
+
+type testParams struct {
+	secret    string
+	name      string
+	issuer    string
+	algorithm uint64
+	digits    uint64
+	otpType   uint64
+	counter   uint64
+}
+
+func encodePayload(params []testParams, batchSize, batchIndex, batchID uint64) string {
+	var payload []byte
+	for _, p := range params {
+		var message []byte
+		message = appendBytesField(message, 1, []byte(p.secret))
+		message = appendBytesField(message, 2, []byte(p.name))
+		message = appendBytesField(message, 3, []byte(p.issuer))
+		message = appendVarintField(message, 4, p.algorithm)
+		message = appendVarintField(message, 5, p.digits)
+		message = appendVarintField(message, 6, p.otpType)
+		message = appendVarintField(message, 7, p.counter)
+		payload = appendBytesField(payload, 1, message)
+	}
+	payload = appendVarintField(payload, 2, 1)
+	payload = appendVarintField(payload, 3, batchSize)
+	payload = appendVarintField(payload, 4, batchIndex)
+	payload = appendVarintField(payload, 5, batchID)
+
+	return "otpauth-migration://offline?data=" + url.QueryEscape(base64.StdEncoding.EncodeToString(payload))
+}
+
+func TestDecodeSample(t *testing.T) {
+	payload, err := Decode(sampleURI)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if len(payload.Accounts) != 1 {
+		t.Fatalf("Expected 1 account, got %d", len(payload.Accounts))
+	}
+
+	expected := types.Account{
+		Name:      "Example-alice@google.com",
+		Issuer:    "Example",
+		Secret:    "JBSWY3DPEHPK3PXP",
+		Type:      types.AccountTypeTOTP,
+		Algorithm: "SHA1",
+		Digits:    6,
+	}
+	if payload.Accounts[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, payload.Accounts[0])
+	}
+}
+
+func TestDecodeParameters(t *testing.T) {
+	uri := encodePayload([]testParams{
+		{secret: "12345678901234567890", name: "alice", issuer: "Bank", algorithm: 2, digits: 2, otpType: 2},
+		{secret: "12345678901234567890", name: "VPN:bob", algorithm: 3, digits: 1, otpType: 1, counter: 9},
+	}, 1, 0, 42)
+
+	payload, err := Decode(uri)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if payload.BatchID != 42 || payload.BatchSize != 1 || payload.Version != 1 {
+		t.Errorf("Unexpected batch metadata: %+v", payload)
+	}
+
+	expected := []types.Account{
+		{Name: "Bank-alice", Issuer: "Bank", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: types.AccountTypeTOTP, Algorithm: "SHA256", Digits: 8},
+		{Name: "VPN-bob", Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Type: types.AccountTypeHOTP, Algorithm: "SHA512", Digits: 6, Counter: 9},
+	}
+	if len(payload.Accounts) != len(expected) {
+		t.Fatalf("Expected %d accounts, got %d", len(expected), len(payload.Accounts))
+	}
+	for i := range expected {
+		if payload.Accounts[i] != expected[i] {
+			t.Errorf("Account %d: expected %+v, got %+v", i, expected[i], payload.Accounts[i])
+		}
+	}
+}
+
+func TestDecodeAllMultiBatch(t *testing.T) {
+	first := encodePayload([]testParams{{secret: "first-secret", name: "one"}}, 2, 0, 7)
+	second := encodePayload([]testParams{{secret: "second-secret", name: "two"}}, 2, 1, 7)
+
+	accounts, err := DecodeAll([]string{second, first})
+	if err != nil {
+		t.Fatalf("DecodeAll failed: %v", err)
+	}
+
+	if len(accounts) != 2 || accounts[0].Name != "one" || accounts[1].Name != "two" {
+		t.Errorf("Expected accounts in batch order, got %+v", accounts)
+	}
+
+	if _, err := DecodeAll([]string{first}); err == nil {
+		t.Error("Expected error when a batch is missing")
+	}
+
+	if _, err := DecodeAll([]string{first, first, second}); err == nil {
+		t.Error("Expected error when a batch is repeated")
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	invalid := []string{
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP",
+		"otpauth-migration://offline",
+		"otpauth-migration://offline?data=!!!",
+		"otpauth-migration://offline?data=" + base64.StdEncoding.EncodeToString([]byte{0x0a, 0x10, 0x01}),
+		encodePayload([]testParams{{secret: "", name: "empty"}}, 1, 0, 1),
+		encodePayload([]testParams{{secret: "s", name: "x", digits: 9}}, 1, 0, 1),
+		encodePayload([]testParams{{secret: "s", name: "x"}}, 2, 2, 1),
+	}
+
+	for _, uri := range invalid {
+		if _, err := Decode(uri); err == nil {
+			t.Errorf("Expected error decoding %s", uri)
+		}
+	}
+}
+
+func TestDecodeUnescapedPlus(t *testing.T) {
+	// "+" characters that were not percent-encoded come back as spaces.
+	payload := encodePayload([]testParams{{secret: "\xe0\xef\xbe", name: "plus"}}, 1, 0, 1)
+	unescaped, err := url.QueryUnescape(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(unescaped, "+") {
+		t.Fatalf("Test payload should contain '+', got %s", unescaped)
+	}
+
+	if _, err := Decode(unescaped); err != nil {
+		t.Errorf("Decode failed for unescaped data: %v", err)
+	}
+}
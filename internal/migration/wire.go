@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated data")
+
+// reader is a minimal protobuf wire format decoder, just enough for the
+// migration payload messages.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) varint() (uint64, error) {
+	var value uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		if r.pos >= len(r.data) {
+			return 0, errTruncated
+		}
+		b := r.data[r.pos]
+		r.pos++
+		value |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("varint overflow")
+}
+
+func (r *reader) tag() (field int, wireType int, err error) {
+	value, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	field = int(value >> 3)
+	if field == 0 {
+		return 0, 0, fmt.Errorf("invalid field number 0")
+	}
+	return field, int(value & 0x7), nil
+}
+
+func (r *reader) bytes() ([]byte, error) {
+	length, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.data)-r.pos) {
+		return nil, errTruncated
+	}
+	value := r.data[r.pos : r.pos+int(length)]
+	r.pos += int(length)
+	return value, nil
+}
+
+func (r *reader) skip(wireType int) error {
+	switch wireType {
+	case wireVarint:
+		_, err := r.varint()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	case wireFixed64:
+		return r.advance(8)
+	case wireFixed32:
+		return r.advance(4)
+	default:
+		return fmt.Errorf("unsupported wire type %d", wireType)
+	}
+}
+
+func (r *reader) advance(n int) error {
+	if n > len(r.data)-r.pos {
+		return errTruncated
+	}
+	r.pos += n
+	return nil
+}
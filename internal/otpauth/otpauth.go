@@ -0,0 +1,171 @@
+package otpauth
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"mf/internal/types"
+)
+
+const Scheme = "otpauth"
+
+// IsURI reports whether s looks like an otpauth:// key URI.
+func IsURI(s string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(s)), Scheme+"://")
+}
+
+// Parse decodes a Key URI as described by the Google Authenticator
+// key-uri-format:
+//
+//	otpauth://TYPE/ISSUER:LABEL?secret=SECRET&issuer=ISSUER&algorithm=SHA1&digits=6&period=30
+//
+// TOTP URIs issued by Steam (issuer=Steam or encoder=steam) become Steam
+// Guard accounts.
+// The returned account is named after the issuer and label; callers may
+// override the name before storing it.
+func Parse(uri string) (*types.Account, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return nil, fmt.Errorf("invalid otpauth URI: %w", err)
+	}
+
+	if !strings.EqualFold(u.Scheme, Scheme) {
+		return nil, fmt.Errorf("invalid otpauth URI: unexpected scheme '%s'", u.Scheme)
+	}
+
+	accountType := strings.ToLower(u.Host)
+	if accountType != types.AccountTypeTOTP && accountType != types.AccountTypeHOTP && accountType != types.AccountTypeSteam {
+		return nil, fmt.Errorf("invalid otpauth URI: unsupported type '%s'", u.Host)
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	labelIssuer, _ := splitLabel(label)
+
+	query := u.Query()
+	issuer := strings.TrimSpace(query.Get("issuer"))
+	if issuer == "" {
+		issuer = labelIssuer
+	}
+
+	secret := strings.ToUpper(strings.TrimSpace(query.Get("secret")))
+	if secret == "" {
+		return nil, fmt.Errorf("invalid otpauth URI: missing secret")
+	}
+
+	// Steam Guard accounts are exported as TOTP URIs, marked either by the
+	// Steam issuer or by the encoder parameter used by Aegis and andOTP.
+	if accountType == types.AccountTypeTOTP &&
+		(strings.EqualFold(issuer, "Steam") || strings.EqualFold(query.Get("encoder"), "steam")) {
+		accountType = types.AccountTypeSteam
+	}
+
+	account := &types.Account{
+		Name:      AccountName(issuer, label),
+		Issuer:    issuer,
+		Secret:    secret,
+		Type:      accountType,
+		Algorithm: strings.ToUpper(query.Get("algorithm")),
+	}
+
+	if account.Name == "" {
+		return nil, fmt.Errorf("invalid otpauth URI: missing label")
+	}
+
+	if digits := query.Get("digits"); digits != "" {
+		account.Digits, err = strconv.Atoi(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otpauth URI: invalid digits '%s'", digits)
+		}
+	}
+
+	if period := query.Get("period"); period != "" {
+		account.Period, err = strconv.Atoi(period)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otpauth URI: invalid period '%s'", period)
+		}
+	}
+
+	if accountType == types.AccountTypeHOTP {
+		counter := query.Get("counter")
+		if counter == "" {
+			return nil, fmt.Errorf("invalid otpauth URI: missing counter for hotp")
+		}
+		account.Counter, err = strconv.ParseUint(counter, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid otpauth URI: invalid counter '%s'", counter)
+		}
+	}
+
+	return account, nil
+}
+
+// Format builds the otpauth:// URI for an account, the inverse of Parse.
+// T0 has no representation in the key URI format and is not included.
+func Format(account types.Account) string {
+	label := account.Name
+	if account.Issuer != "" {
+		label = account.Issuer + ":" + strings.TrimPrefix(account.Name, account.Issuer+"-")
+	}
+
+	query := url.Values{}
+	query.Set("secret", account.Secret)
+	if account.Issuer != "" {
+		query.Set("issuer", account.Issuer)
+	}
+	if account.Algorithm != "" {
+		query.Set("algorithm", strings.ToUpper(account.Algorithm))
+	}
+	if account.Digits != 0 {
+		query.Set("digits", strconv.Itoa(account.Digits))
+	}
+
+	accountType := account.AccountType()
+	switch accountType {
+	case types.AccountTypeHOTP:
+		query.Set("counter", strconv.FormatUint(account.Counter, 10))
+	case types.AccountTypeSteam:
+		accountType = types.AccountTypeTOTP
+		query.Set("encoder", "steam")
+	default:
+		if account.Period != 0 {
+			query.Set("period", strconv.Itoa(account.Period))
+		}
+	}
+
+	u := url.URL{
+		Scheme: Scheme,
+		Host:   accountType,
+		Path:   "/" + label,
+		// Authenticator apps are more reliable with %20 than with '+'.
+		RawQuery: strings.ReplaceAll(query.Encode(), "+", "%20"),
+	}
+	return u.String()
+}
+
+func splitLabel(label string) (issuer, accountName string) {
+	if i := strings.Index(label, ":"); i >= 0 {
+		return strings.TrimSpace(label[:i]), strings.TrimSpace(label[i+1:])
+	}
+	return "", strings.TrimSpace(label)
+}
+
+// AccountName derives the local account name from an issuer and an
+// "Issuer:label" style label, e.g. "GitHub" and "GitHub:octocat" become
+// "GitHub-octocat".
+func AccountName(issuer, label string) string {
+	labelIssuer, accountName := splitLabel(label)
+	if issuer == "" {
+		issuer = labelIssuer
+	}
+
+	switch {
+	case issuer == "":
+		return accountName
+	case accountName == "":
+		return issuer
+	default:
+		return issuer + "-" + accountName
+	}
+}
@@ -0,0 +1,156 @@
+package otpauth
+
+import (
+	"testing"
+
+	"mf/internal/types"
+)
+
+func TestParseTOTP(t *testing.T) {
+	uri := "otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA256&digits=8&period=60"
+
+	account, err := Parse(uri)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := types.Account{
+		Name:      "ACME Co-john.doe@email.com",
+		Issuer:    "ACME Co",
+		Secret:    "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+		Type:      types.AccountTypeTOTP,
+		Algorithm: "SHA256",
+		Digits:    8,
+		Period:    60,
+	}
+
+	if *account != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *account)
+	}
+}
+
+func TestParseDefaultsAndLabelIssuer(t *testing.T) {
+	account, err := Parse("otpauth://totp/GitHub:octocat?secret=jbswy3dpehpk3pxp")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if account.Name != "GitHub-octocat" {
+		t.Errorf("Expected name GitHub-octocat, got %s", account.Name)
+	}
+	if account.Issuer != "GitHub" {
+		t.Errorf("Expected issuer GitHub, got %s", account.Issuer)
+	}
+	if account.Secret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Expected uppercase secret, got %s", account.Secret)
+	}
+	if account.Algorithm != "" || account.Digits != 0 || account.Period != 0 {
+		t.Errorf("Expected default parameters to be left empty, got %+v", account)
+	}
+}
+
+func TestParseLabelWithoutIssuer(t *testing.T) {
+	account, err := Parse("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if account.Name != "alice" || account.Issuer != "" {
+		t.Errorf("Expected name alice without issuer, got %+v", account)
+	}
+}
+
+func TestParseHOTP(t *testing.T) {
+	account, err := Parse("otpauth://hotp/VPN:bob?secret=JBSWY3DPEHPK3PXP&counter=42")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if account.Type != types.AccountTypeHOTP {
+		t.Errorf("Expected type hotp, got %s", account.Type)
+	}
+	if account.Counter != 42 {
+		t.Errorf("Expected counter 42, got %d", account.Counter)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	invalid := []string{
+		"https://example.com/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://unknown/label?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/label",
+		"otpauth://totp/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/label?secret=JBSWY3DPEHPK3PXP&digits=six",
+		"otpauth://totp/label?secret=JBSWY3DPEHPK3PXP&period=abc",
+		"otpauth://hotp/label?secret=JBSWY3DPEHPK3PXP",
+	}
+
+	for _, uri := range invalid {
+		if _, err := Parse(uri); err == nil {
+			t.Errorf("Expected error parsing %s", uri)
+		}
+	}
+}
+
+func TestIsURI(t *testing.T) {
+	if !IsURI("  OTPAUTH://totp/x?secret=A") {
+		t.Error("Expected otpauth URI to be detected")
+	}
+	if IsURI("JBSWY3DPEHPK3PXP") {
+		t.Error("Plain secret should not be detected as URI")
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	accounts := []types.Account{
+		{Name: "ACME Co-john.doe@email.com", Issuer: "ACME Co", Secret: "JBSWY3DPEHPK3PXP", Type: types.AccountTypeTOTP, Algorithm: "SHA256", Digits: 8, Period: 60},
+		{Name: "alice", Secret: "JBSWY3DPEHPK3PXP", Type: types.AccountTypeTOTP},
+		{Name: "VPN-bob", Issuer: "VPN", Secret: "JBSWY3DPEHPK3PXP", Type: types.AccountTypeHOTP, Counter: 42},
+	}
+
+	for _, account := range accounts {
+		uri := Format(account)
+		parsed, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", uri, err)
+		}
+		if *parsed != account {
+			t.Errorf("Round trip through %s: expected %+v, got %+v", uri, account, *parsed)
+		}
+	}
+}
+
+func TestFormatLegacyAccount(t *testing.T) {
+	uri := Format(types.Account{Name: "AWS DEV", Secret: "JBSWY3DPEHPK3PXP"})
+	expected := "otpauth://totp/AWS%20DEV?secret=JBSWY3DPEHPK3PXP"
+	if uri != expected {
+		t.Errorf("Expected %s, got %s", expected, uri)
+	}
+}
+
+func TestParseSteam(t *testing.T) {
+	uris := []string{
+		"otpauth://totp/Steam:gaben?secret=JBSWY3DPEHPK3PXP&issuer=Steam",
+		"otpauth://totp/gaben?secret=JBSWY3DPEHPK3PXP&encoder=steam",
+		"otpauth://steam/Steam:gaben?secret=JBSWY3DPEHPK3PXP",
+	}
+
+	for _, uri := range uris {
+		account, err := Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", uri, err)
+		}
+		if account.Type != types.AccountTypeSteam {
+			t.Errorf("Parse(%s): expected type steam, got %s", uri, account.Type)
+		}
+	}
+
+	steam := types.Account{Name: "Steam-gaben", Issuer: "Steam", Secret: "JBSWY3DPEHPK3PXP", Type: types.AccountTypeSteam}
+	parsed, err := Parse(Format(steam))
+	if err != nil {
+		t.Fatalf("Parse(Format(steam)) failed: %v", err)
+	}
+	if *parsed != steam {
+		t.Errorf("Expected %+v, got %+v", steam, *parsed)
+	}
+}
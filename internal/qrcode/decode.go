@@ -0,0 +1,73 @@
+package qrcode
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+
+	"github.com/makiuchi-d/gozxing"
+	multiqr "github.com/makiuchi-d/gozxing/multi/qrcode"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// DecodeFile reads a PNG, JPEG or GIF image and returns the text of every QR
+// code found in it.
+func DecodeFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	return Decode(img)
+}
+
+// Decode returns the text of every QR code found in img, in the order they
+// were detected. Screenshots with several codes (such as multi-batch
+// Google Authenticator exports) yield one entry per code.
+func Decode(img image.Image) ([]string, error) {
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+
+	var texts []string
+	seen := make(map[string]bool)
+	add := func(text string) {
+		if text != "" && !seen[text] {
+			seen[text] = true
+			texts = append(texts, text)
+		}
+	}
+
+	results, err := multiqr.NewQRCodeMultiReader().DecodeMultiple(bitmap, hints)
+	if err == nil {
+		for _, result := range results {
+			add(result.GetText())
+		}
+	}
+
+	// The multi reader occasionally misses a lone code that the regular
+	// reader finds, so fall back to it before giving up.
+	if len(texts) == 0 {
+		result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+		if err != nil {
+			return nil, fmt.Errorf("no QR code found in image")
+		}
+		add(result.GetText())
+	}
+
+	return texts, nil
+}
@@ -0,0 +1,102 @@
+package qrcode
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+func encodeQR(t *testing.T, content string, size int) image.Image {
+	t.Helper()
+
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		t.Fatalf("Failed to encode QR code: %v", err)
+	}
+	code, err = barcode.Scale(code, size, size)
+	if err != nil {
+		t.Fatalf("Failed to scale QR code: %v", err)
+	}
+	return code
+}
+
+func TestDecodeFileSingle(t *testing.T) {
+	uri := "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"
+
+	path := filepath.Join(t.TempDir(), "code.png")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, withMargin(encodeQR(t, uri, 300), 40)); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	texts, err := DecodeFile(path)
+	if err != nil {
+		t.Fatalf("DecodeFile failed: %v", err)
+	}
+
+	if len(texts) != 1 || texts[0] != uri {
+		t.Errorf("Expected [%s], got %v", uri, texts)
+	}
+}
+
+func TestDecodeMultiple(t *testing.T) {
+	uris := []string{
+		"otpauth://totp/A:one?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/B:two?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+	}
+
+	canvas := image.NewGray(image.Rect(0, 0, 760, 380))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(40, 40, 340, 340), encodeQR(t, uris[0], 300), image.Point{}, draw.Src)
+	draw.Draw(canvas, image.Rect(420, 40, 720, 340), encodeQR(t, uris[1], 300), image.Point{}, draw.Src)
+
+	texts, err := Decode(canvas)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	sort.Strings(texts)
+	if len(texts) != 2 || texts[0] != uris[0] || texts[1] != uris[1] {
+		t.Errorf("Expected %v, got %v", uris, texts)
+	}
+}
+
+func TestDecodeWithoutQRCode(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 100, 100))
+	draw.Draw(blank, blank.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+
+	if _, err := Decode(blank); err == nil {
+		t.Error("Expected error for image without QR code")
+	}
+}
+
+func TestDecodeFileInvalidImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-an-image.png")
+	if err := os.WriteFile(path, []byte("not an image"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DecodeFile(path); err == nil {
+		t.Error("Expected error for invalid image file")
+	}
+}
+
+func withMargin(img image.Image, margin int) image.Image {
+	bounds := img.Bounds()
+	canvas := image.NewGray(image.Rect(0, 0, bounds.Dx()+2*margin, bounds.Dy()+2*margin))
+	draw.Draw(canvas, canvas.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(canvas, bounds.Add(image.Pt(margin, margin)), img, bounds.Min, draw.Src)
+	return canvas
+}
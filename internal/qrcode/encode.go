@@ -0,0 +1,149 @@
+package qrcode
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// quietZone is the blank border, in modules, required around a QR code.
+const quietZone = 4
+
+// matrix is a QR code as a grid of dark (true) and light (false) modules,
+// including the quiet zone.
+type matrix [][]bool
+
+func encodeMatrix(content string) (matrix, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	bounds := code.Bounds()
+	size := bounds.Dx() + 2*quietZone
+	m := make(matrix, size)
+	for y := range m {
+		m[y] = make([]bool, size)
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			m[y-bounds.Min.Y+quietZone][x-bounds.Min.X+quietZone] = isDark(code.At(x, y))
+		}
+	}
+
+	return m, nil
+}
+
+func isDark(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r+g+b < 3*0x8000
+}
+
+// RenderTerminal draws the QR code with Unicode half blocks, two modules per
+// character row. By default light modules are drawn as filled blocks, which
+// scans correctly on the usual light-text-on-dark-background terminal; set
+// invert for dark text on a light background.
+func RenderTerminal(content string, invert bool) (string, error) {
+	m, err := encodeMatrix(content)
+	if err != nil {
+		return "", err
+	}
+
+	filled := func(y, x int) bool {
+		if y >= len(m) {
+			return !invert
+		}
+		return m[y][x] == invert
+	}
+
+	var sb strings.Builder
+	for y := 0; y < len(m); y += 2 {
+		for x := range m[y] {
+			top, bottom := filled(y, x), filled(y+1, x)
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String(), nil
+}
+
+// WritePNG writes the QR code as a size x size PNG image.
+func WritePNG(w io.Writer, content string, size int) error {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+
+	modules := code.Bounds().Dx() + 2*quietZone
+	if size < modules {
+		size = modules
+	}
+	// Scale to a whole number of pixels per module and pad the rest with the
+	// quiet zone so the code stays sharp.
+	inner := size / modules * (modules - 2*quietZone)
+	scaled, err := barcode.Scale(code, inner, inner)
+	if err != nil {
+		return fmt.Errorf("failed to scale QR code: %w", err)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	offset := (size - inner) / 2
+	for y := 0; y < inner; y++ {
+		for x := 0; x < inner; x++ {
+			if isDark(scaled.At(x, y)) {
+				img.SetGray(x+offset, y+offset, color.Gray{Y: 0})
+			}
+		}
+	}
+
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to write PNG: %w", err)
+	}
+	return nil
+}
+
+// WriteSVG writes the QR code as a scalable SVG document.
+func WriteSVG(w io.Writer, content string) error {
+	m, err := encodeMatrix(content)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", len(m), len(m))
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", len(m), len(m))
+	sb.WriteString(`<path fill="#000000" d="`)
+	for y, row := range m {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&sb, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	sb.WriteString(`"/>` + "\n</svg>\n")
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("failed to write SVG: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+const testURI = "otpauth://totp/GitHub:octocat?secret=JBSWY3DPEHPK3PXP&issuer=GitHub"
+
+func TestWritePNGRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePNG(&buf, testURI, 256); err != nil {
+		t.Fatalf("WritePNG failed: %v", err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Output is not a valid PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 256 || img.Bounds().Dy() != 256 {
+		t.Errorf("Expected 256x256 image, got %v", img.Bounds())
+	}
+
+	texts, err := Decode(img)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(texts) != 1 || texts[0] != testURI {
+		t.Errorf("Expected [%s], got %v", testURI, texts)
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, testURI); err != nil {
+		t.Fatalf("WriteSVG failed: %v", err)
+	}
+
+	svg := buf.String()
+	if !strings.HasPrefix(svg, "<svg ") || !strings.Contains(svg, "</svg>") {
+		t.Errorf("Output does not look like an SVG document: %s", svg)
+	}
+	if !strings.Contains(svg, "h1v1h-1z") {
+		t.Error("SVG should contain dark modules")
+	}
+}
+
+func TestRenderTerminal(t *testing.T) {
+	m, err := encodeMatrix(testURI)
+	if err != nil {
+		t.Fatalf("encodeMatrix failed: %v", err)
+	}
+
+	out, err := RenderTerminal(testURI, false)
+	if err != nil {
+		t.Fatalf("RenderTerminal failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != (len(m)+1)/2 {
+		t.Errorf("Expected %d lines, got %d", (len(m)+1)/2, len(lines))
+	}
+	for _, line := range lines {
+		if n := len([]rune(line)); n != len(m) {
+			t.Fatalf("Expected %d columns, got %d", len(m), n)
+		}
+	}
+
+	// The quiet zone is light, so the first row is fully drawn by default
+	// and blank when inverted.
+	if strings.Trim(lines[0], "█") != "" {
+		t.Errorf("Expected quiet zone to be filled, got %q", lines[0])
+	}
+
+	inverted, err := RenderTerminal(testURI, true)
+	if err != nil {
+		t.Fatalf("RenderTerminal inverted failed: %v", err)
+	}
+	if strings.TrimSpace(strings.SplitN(inverted, "\n", 2)[0]) != "" {
+		t.Error("Expected quiet zone to be blank when inverted")
+	}
+}
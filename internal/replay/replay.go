@@ -0,0 +1,82 @@
+// Package replay keeps a small local record of codes that were already
+// accepted by mf verify, so a second use of the same code can be flagged.
+//
+// Entries are stored as SHA-256 hashes of the secret and the step the code
+// belongs to; neither secrets nor codes are written to disk.
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"mf/internal/fsutil"
+)
+
+type Cache struct {
+	path    string
+	Entries map[string]int64 `json:"entries"`
+}
+
+// Open loads the cache at path. A missing file yields an empty cache.
+func Open(path string) (*Cache, error) {
+	cache := &Cache{path: path, Entries: make(map[string]int64)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("failed to read replay cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("failed to parse replay cache: %w", err)
+	}
+	if cache.Entries == nil {
+		cache.Entries = make(map[string]int64)
+	}
+
+	return cache, nil
+}
+
+// Key identifies a code by the secret it was generated from and the
+// counter/step it is valid for.
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Seen reports whether key was recorded and has not expired yet.
+func (c *Cache) Seen(key string, now time.Time) bool {
+	expires, ok := c.Entries[key]
+	return ok && now.Unix() < expires
+}
+
+// Add records key until expires.
+func (c *Cache) Add(key string, expires time.Time) {
+	c.Entries[key] = expires.Unix()
+}
+
+// Save drops expired entries and writes the cache back to disk.
+func (c *Cache) Save(now time.Time) error {
+	for key, expires := range c.Entries {
+		if now.Unix() >= expires {
+			delete(c.Entries, key)
+		}
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal replay cache: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write replay cache: %w", err)
+	}
+	return nil
+}
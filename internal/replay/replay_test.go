@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "used-codes.json")
+	now := time.Unix(1700000000, 0)
+
+	cache, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	key := Key("totp", "JBSWY3DPEHPK3PXP", "56666666")
+	if cache.Seen(key, now) {
+		t.Fatal("Empty cache should not contain key")
+	}
+
+	cache.Add(key, now.Add(90*time.Second))
+	cache.Add(Key("totp", "JBSWY3DPEHPK3PXP", "1"), now.Add(-time.Second))
+	if err := cache.Save(now); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if !reopened.Seen(key, now) {
+		t.Error("Expected key to be seen after reopening")
+	}
+	if reopened.Seen(key, now.Add(90*time.Second)) {
+		t.Error("Expected key to expire")
+	}
+	if len(reopened.Entries) != 1 {
+		t.Errorf("Expected expired entries to be pruned, got %d entries", len(reopened.Entries))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "JBSWY3DPEHPK3PXP") {
+		t.Error("Cache file must not contain the secret")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestKeyDistinguishesParts(t *testing.T) {
+	if Key("ab", "c") == Key("a", "bc") {
+		t.Error("Keys with different parts should differ")
+	}
+}
+
+func TestOpenInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "used-codes.json")
+	if err := os.WriteFile(path, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(path); err == nil {
+		t.Error("Expected error for corrupted cache")
+	}
+}
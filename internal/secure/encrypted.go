@@ -1,23 +1,37 @@
 package secure
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"sort"
 
-	"golang.org/x/crypto/pbkdf2"
+	"mf/internal/config"
+	"mf/internal/fsutil"
 	"mf/internal/types"
 )
 
+const (
+	vaultFile    = "vault.enc"
+	vaultVersion = 1
+)
+
+// EncryptedStorage keeps every account in a single encrypted vault file, so
+// account names are not visible in the configuration directory.
 type EncryptedStorage struct {
 	configDir string
-	key       []byte
+	// key is the random data key the vault is encrypted with.
+	key []byte
+	// legacyMaterial is the mf 2.0 machine key, used only to read files
+	// written before the data key was introduced.
+	legacyMaterial []byte
+}
+
+// vault is the decrypted content of the vault file.
+type vault struct {
+	Version  int                      `json:"version"`
+	Accounts map[string]types.Account `json:"accounts"`
 }
 
 type EncryptedProvider struct{}
@@ -27,14 +41,24 @@ func (p *EncryptedProvider) IsAvailable() bool {
 }
 
 func (p *EncryptedProvider) GetStorage() (SecureStorage, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Load()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+		return nil, err
+	}
+
+	source, err := newKeySource(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	configDir := filepath.Join(homeDir, ".config", "mf")
-	if err := os.MkdirAll(configDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	dataKey, err := loadDataKey(configDir, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load vault key: %w", err)
 	}
 
 	machineKey, err := GetMachineKey()
@@ -42,157 +66,321 @@ func (p *EncryptedProvider) GetStorage() (SecureStorage, error) {
 		return nil, fmt.Errorf("failed to get machine key: %w", err)
 	}
 
-	key := pbkdf2.Key(machineKey, []byte("mf-salt"), 10000, 32, sha256.New)
-
 	return &EncryptedStorage{
-		configDir: configDir,
-		key:       key,
+		configDir:      configDir,
+		key:            dataKey,
+		legacyMaterial: machineKey,
 	}, nil
 }
 
 func (e *EncryptedStorage) Store(account types.Account) error {
-	data, err := json.Marshal(account)
-	if err != nil {
-		return fmt.Errorf("failed to marshal account: %w", err)
-	}
+	return withLock(e.configDir, func() error {
+		v, err := e.load()
+		if err != nil {
+			return err
+		}
 
-	encryptedData, err := e.encrypt(data)
-	if err != nil {
-		return fmt.Errorf("failed to encrypt account data: %w", err)
-	}
+		v.Accounts[account.Name] = account
+		return e.save(v)
+	})
+}
 
-	filename := filepath.Join(e.configDir, account.Name+".enc")
-	if err := os.WriteFile(filename, encryptedData, 0600); err != nil {
-		return fmt.Errorf("failed to write encrypted account file: %w", err)
-	}
+// StoreAll stores a batch of accounts with a single write of the vault, so
+// either all of them are saved or none is.
+func (e *EncryptedStorage) StoreAll(accounts []types.Account) error {
+	return withLock(e.configDir, func() error {
+		v, err := e.load()
+		if err != nil {
+			return err
+		}
 
-	e.removeOldFormat(account.Name)
-	return nil
+		for _, account := range accounts {
+			v.Accounts[account.Name] = account
+		}
+		return e.save(v)
+	})
 }
 
 func (e *EncryptedStorage) Retrieve(name string) (*types.Account, error) {
-	filename := filepath.Join(e.configDir, name+".enc")
-	encryptedData, err := os.ReadFile(filename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			if account, legacyErr := e.tryLoadLegacy(name); legacyErr == nil {
-				e.Store(*account)
-				return account, nil
-			}
-			return nil, fmt.Errorf("account '%s' not found", name)
+	var account types.Account
+	err := withLock(e.configDir, func() error {
+		v, err := e.load()
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		if account, ok = v.Accounts[name]; !ok {
+			return fmt.Errorf("account '%s' not found", name)
 		}
-		return nil, fmt.Errorf("failed to read encrypted account file: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &account, nil
+}
 
-	data, err := e.decrypt(encryptedData)
+func (e *EncryptedStorage) List() ([]string, error) {
+	var accounts []string
+	err := withLock(e.configDir, func() error {
+		v, err := e.load()
+		if err != nil {
+			return err
+		}
+
+		accounts = make([]string, 0, len(v.Accounts))
+		for name := range v.Accounts {
+			accounts = append(accounts, name)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt account data: %w", err)
+		return nil, err
 	}
+	sort.Strings(accounts)
+	return accounts, nil
+}
+
+func (e *EncryptedStorage) Delete(name string) error {
+	return withLock(e.configDir, func() error {
+		v, err := e.load()
+		if err != nil {
+			return err
+		}
 
+		if _, ok := v.Accounts[name]; !ok {
+			return fmt.Errorf("account '%s' not found", name)
+		}
+		delete(v.Accounts, name)
+		return e.save(v)
+	})
+}
+
+// Update applies fn to an account and stores the result, holding the lock
+// for the whole sequence so concurrent processes cannot lose the change.
+func (e *EncryptedStorage) Update(name string, fn func(account *types.Account) error) (*types.Account, error) {
 	var account types.Account
-	if err := json.Unmarshal(data, &account); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal account data: %w", err)
-	}
+	err := withLock(e.configDir, func() error {
+		v, err := e.load()
+		if err != nil {
+			return err
+		}
 
+		var ok bool
+		if account, ok = v.Accounts[name]; !ok {
+			return fmt.Errorf("account '%s' not found", name)
+		}
+		if err := fn(&account); err != nil {
+			return err
+		}
+
+		v.Accounts[account.Name] = account
+		return e.save(v)
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &account, nil
 }
 
-func (e *EncryptedStorage) List() ([]string, error) {
-	entries, err := os.ReadDir(e.configDir)
+// withLock runs fn while holding the lock on the configuration directory, so
+// the read-modify-write sequences of concurrent mf processes do not
+// interleave. fn must not take the lock again.
+func withLock(configDir string, fn func() error) error {
+	lock, err := fsutil.LockDir(configDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config directory: %w", err)
+		return err
 	}
+	defer lock.Unlock()
+	return fn()
+}
 
-	var accounts []string
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			name := entry.Name()
-			if filepath.Ext(name) == ".enc" {
-				accountName := name[:len(name)-4]
-				accounts = append(accounts, accountName)
-			} else if filepath.Ext(name) == ".json" {
-				accountName := name[:len(name)-5]
-				accounts = append(accounts, accountName)
+// load reads and decrypts the vault, merging in any account files left by
+// previous versions. A missing vault is an empty one.
+func (e *EncryptedStorage) load() (*vault, error) {
+	v := &vault{Version: vaultVersion, Accounts: make(map[string]types.Account)}
+
+	encryptedData, err := os.ReadFile(e.vaultPath())
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read vault file: %w", err)
+	default:
+		data, legacy, err := e.decryptVersioned(encryptedData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt vault: %w", err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal vault: %w", err)
+		}
+		if v.Accounts == nil {
+			v.Accounts = make(map[string]types.Account)
+		}
+
+		// Re-encrypt vaults written with the machine key right away, so
+		// the weaker copy does not stay on disk.
+		if legacy {
+			if err := e.save(v); err != nil {
+				return nil, fmt.Errorf("failed to upgrade vault: %w", err)
 			}
 		}
 	}
 
-	return accounts, nil
+	if err := e.migrateLegacy(v); err != nil {
+		return nil, err
+	}
+	return v, nil
 }
 
-func (e *EncryptedStorage) Delete(name string) error {
-	filename := filepath.Join(e.configDir, name+".enc")
-	if err := os.Remove(filename); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("account '%s' not found", name)
-		}
-		return fmt.Errorf("failed to delete encrypted account file: %w", err)
+func (e *EncryptedStorage) save(v *vault) error {
+	v.Version = vaultVersion
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vault: %w", err)
 	}
 
-	e.removeOldFormat(name)
+	encryptedData, err := e.encrypt(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt vault: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(e.vaultPath(), encryptedData, 0600); err != nil {
+		return fmt.Errorf("failed to write vault file: %w", err)
+	}
 	return nil
 }
 
+func (e *EncryptedStorage) vaultPath() string {
+	return filepath.Join(e.configDir, vaultFile)
+}
+
+// encrypt seals data in a versioned envelope with the data key.
 func (e *EncryptedStorage) encrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(e.key)
-	if err != nil {
-		return nil, err
+	return sealEnvelope(e.key, kdfParams{ID: kdfNone}, data)
+}
+
+// decrypt opens an envelope sealed with the data key. Files from earlier
+// versions, either headerless or with a key derived from the machine key,
+// are opened with the legacy key material.
+func (e *EncryptedStorage) decrypt(data []byte) ([]byte, error) {
+	data, _, err := e.decryptVersioned(data)
+	return data, err
+}
+
+// decryptVersioned is decrypt that also reports whether the data used a
+// legacy key and should be rewritten.
+func (e *EncryptedStorage) decryptVersioned(data []byte) ([]byte, bool, error) {
+	if !isEnvelope(data) {
+		plaintext, err := openLegacy(e.legacyMaterial, data)
+		return plaintext, true, err
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	env, err := parseEnvelope(data)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return nil, err
+	if env.KDF.ID == kdfNone {
+		plaintext, err := env.open(e.key)
+		if err != nil {
+			return nil, false, fmt.Errorf("vault key does not match the vault: %w", err)
+		}
+		return plaintext, false, nil
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
-	return ciphertext, nil
+	key, err := env.KDF.deriveKey(e.legacyMaterial)
+	if err != nil {
+		return nil, false, err
+	}
+	plaintext, err := env.open(key)
+	return plaintext, true, err
 }
 
-func (e *EncryptedStorage) decrypt(data []byte) ([]byte, error) {
-	block, err := aes.NewCipher(e.key)
+// migrateLegacy moves the per-account ".enc" files and the plain ".json"
+// files of earlier versions into the vault. The old files are only removed
+// once the vault holding their accounts has been written; files that cannot
+// be read are left untouched.
+func (e *EncryptedStorage) migrateLegacy(v *vault) error {
+	entries, err := os.ReadDir(e.configDir)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to read config directory: %w", err)
 	}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, err
+	var migrated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == vaultFile {
+			continue
+		}
+
+		path := filepath.Join(e.configDir, name)
+		var account *types.Account
+		switch filepath.Ext(name) {
+		case ".enc":
+			account, err = e.readLegacyEncrypted(path)
+		case ".json":
+			account, err = readLegacyJSON(path)
+		default:
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		// Accounts already in the vault are newer than their legacy copy.
+		if _, ok := v.Accounts[account.Name]; !ok {
+			v.Accounts[account.Name] = *account
+		}
+		migrated = append(migrated, path)
 	}
 
-	if len(data) < gcm.NonceSize() {
-		return nil, fmt.Errorf("ciphertext too short")
+	if len(migrated) == 0 {
+		return nil
 	}
 
-	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err := e.save(v); err != nil {
+		return fmt.Errorf("failed to migrate account files: %w", err)
+	}
+	for _, path := range migrated {
+		os.Remove(path)
+	}
+	return nil
+}
+
+func (e *EncryptedStorage) readLegacyEncrypted(path string) (*types.Account, error) {
+	encryptedData, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return plaintext, nil
+	data, err := e.decrypt(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLegacyAccount(data)
 }
 
-func (e *EncryptedStorage) tryLoadLegacy(name string) (*types.Account, error) {
-	filename := filepath.Join(e.configDir, name+".json")
-	data, err := os.ReadFile(filename)
+func readLegacyJSON(path string) (*types.Account, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return parseLegacyAccount(data)
+}
 
+// parseLegacyAccount decodes an account file. Other JSON files that share the
+// configuration directory, such as config.json, have no name or secret and
+// are rejected.
+func parseLegacyAccount(data []byte) (*types.Account, error) {
 	var account types.Account
 	if err := json.Unmarshal(data, &account); err != nil {
 		return nil, err
 	}
-
+	if account.Name == "" || account.Secret == "" {
+		return nil, fmt.Errorf("not an account file")
+	}
 	return &account, nil
 }
-
-func (e *EncryptedStorage) removeOldFormat(name string) {
-	oldFile := filepath.Join(e.configDir, name+".json")
-	os.Remove(oldFile)
-}
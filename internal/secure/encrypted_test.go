@@ -1,11 +1,24 @@
 package secure
 
 import (
-	"mf/internal/types"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
 	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+	"mf/internal/fsutil"
+	"mf/internal/types"
 )
 
 func TestEncryptedStorage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
 	provider := &EncryptedProvider{}
 
 	if !provider.IsAvailable() {
@@ -69,6 +82,8 @@ func TestEncryptedStorage(t *testing.T) {
 }
 
 func TestEncryptDecryptRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
 	provider := &EncryptedProvider{}
 	store, err := provider.GetStorage()
 	if err != nil {
@@ -100,3 +115,333 @@ func TestEncryptDecryptRoundTrip(t *testing.T) {
 		t.Errorf("Decrypted data doesn't match original. Expected: %s, Got: %s", originalData, decrypted)
 	}
 }
+
+func TestEncryptedStoragePersistsTOTPParameters(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	provider := &EncryptedProvider{}
+	store, err := provider.GetStorage()
+	if err != nil {
+		t.Fatalf("Failed to get encrypted storage: %v", err)
+	}
+
+	account := types.Account{
+		Name:      "test-encrypted-params",
+		Secret:    "JBSWY3DPEHPK3PXP",
+		Algorithm: "SHA512",
+		Digits:    8,
+		Period:    60,
+		T0:        1000,
+	}
+
+	if err := store.Store(account); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	defer store.Delete(account.Name)
+
+	retrieved, err := store.Retrieve(account.Name)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+
+	if *retrieved != account {
+		t.Errorf("Expected %+v, got %+v", account, *retrieved)
+	}
+}
+
+func newTestEncryptedStorage(t *testing.T) *EncryptedStorage {
+	t.Helper()
+	return &EncryptedStorage{
+		configDir:      t.TempDir(),
+		key:            bytes.Repeat([]byte{0x42}, keySize),
+		legacyMaterial: []byte("test machine key"),
+	}
+}
+
+func TestEncryptedStorageSingleVaultFile(t *testing.T) {
+	store := newTestEncryptedStorage(t)
+
+	for _, name := range []string{"AWS-PROD", "bank"} {
+		if err := store.Store(types.Account{Name: name, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+			t.Fatalf("Store(%s) failed: %v", name, err)
+		}
+	}
+
+	if names := configFiles(t, store.configDir); len(names) != 1 || names[0] != vaultFile {
+		t.Fatalf("Expected only %s in the config directory, got %v", vaultFile, names)
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.configDir, vaultFile))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if bytes.Contains(data, []byte("AWS-PROD")) || bytes.Contains(data, []byte("bank")) {
+		t.Error("Vault file should not reveal account names")
+	}
+
+	accounts, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0] != "AWS-PROD" || accounts[1] != "bank" {
+		t.Errorf("Expected [AWS-PROD bank], got %v", accounts)
+	}
+}
+
+func TestEncryptedStorageStoreAll(t *testing.T) {
+	store := newTestEncryptedStorage(t)
+
+	if err := store.Store(types.Account{Name: "existing", Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	batch := []types.Account{
+		{Name: "AWS-PROD", Secret: "JBSWY3DPEHPK3PXP"},
+		{Name: "bank", Secret: "GEZDGNBVGY3TQOJQ"},
+	}
+	if err := store.StoreAll(batch); err != nil {
+		t.Fatalf("StoreAll failed: %v", err)
+	}
+
+	accounts, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(accounts) != 3 || accounts[0] != "AWS-PROD" || accounts[1] != "bank" || accounts[2] != "existing" {
+		t.Errorf("Expected [AWS-PROD bank existing], got %v", accounts)
+	}
+
+	// A vault that cannot be read must not be overwritten with the batch.
+	if err := os.WriteFile(store.vaultPath(), []byte("corrupt"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := store.StoreAll([]types.Account{{Name: "new", Secret: "JBSWY3DPEHPK3PXP"}}); err == nil {
+		t.Fatal("Expected StoreAll to fail on an unreadable vault")
+	}
+	if data, _ := os.ReadFile(store.vaultPath()); string(data) != "corrupt" {
+		t.Error("StoreAll should leave the vault untouched when it fails")
+	}
+}
+
+func TestEncryptedStorageMigratesLegacyFiles(t *testing.T) {
+	store := newTestEncryptedStorage(t)
+
+	legacyEnc := types.Account{Name: "old-encrypted", Secret: "JBSWY3DPEHPK3PXP"}
+	data, _ := json.Marshal(legacyEnc)
+	writeTestFile(t, filepath.Join(store.configDir, "old-encrypted.enc"), sealLegacy(t, store.legacyMaterial, data))
+
+	legacyJSON := types.Account{Name: "old-plain", Secret: "GEZDGNBVGY3TQOJQ"}
+	data, _ = json.Marshal(legacyJSON)
+	writeTestFile(t, filepath.Join(store.configDir, "old-plain.json"), data)
+
+	// Files that are not accounts must be left alone.
+	writeTestFile(t, filepath.Join(store.configDir, "config.json"), []byte(`{"time_offset_ms":1000}`))
+	writeTestFile(t, filepath.Join(store.configDir, "broken.enc"), []byte("not encrypted"))
+
+	accounts, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(accounts) != 2 || accounts[0] != "old-encrypted" || accounts[1] != "old-plain" {
+		t.Fatalf("Expected migrated accounts, got %v", accounts)
+	}
+
+	for _, expected := range []types.Account{legacyEnc, legacyJSON} {
+		retrieved, err := store.Retrieve(expected.Name)
+		if err != nil {
+			t.Fatalf("Retrieve(%s) failed: %v", expected.Name, err)
+		}
+		if *retrieved != expected {
+			t.Errorf("Expected %+v, got %+v", expected, *retrieved)
+		}
+	}
+
+	for _, name := range []string{"old-encrypted.enc", "old-plain.json"} {
+		if _, err := os.Stat(filepath.Join(store.configDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed after migration", name)
+		}
+	}
+	for _, name := range []string{"config.json", "broken.enc"} {
+		if _, err := os.Stat(filepath.Join(store.configDir, name)); err != nil {
+			t.Errorf("Expected %s to be kept: %v", name, err)
+		}
+	}
+}
+
+func TestEncryptedStorageAdversarialNames(t *testing.T) {
+	storage := newTestEncryptedStorage(t)
+	names := []string{"../../.bashrc", "a/b", `..\..\evil`, "/etc/passwd", "CON", "日本語 🔐", "nul\x00byte"}
+
+	for _, name := range names {
+		if err := storage.Store(types.Account{Name: name, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+			t.Fatalf("Store(%q) failed: %v", name, err)
+		}
+	}
+
+	// Names are keys inside the vault and never become file names.
+	if names := configFiles(t, storage.configDir); len(names) != 1 || names[0] != vaultFile {
+		t.Errorf("Expected only %s in the config directory, got %v", vaultFile, names)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(storage.configDir), ".bashrc")); !os.IsNotExist(err) {
+		t.Error("Store should not write outside the config directory")
+	}
+
+	for _, name := range names {
+		account, err := storage.Retrieve(name)
+		if err != nil {
+			t.Errorf("Retrieve(%q) failed: %v", name, err)
+			continue
+		}
+		if account.Name != name {
+			t.Errorf("Retrieve(%q) returned account %q", name, account.Name)
+		}
+		if err := storage.Delete(name); err != nil {
+			t.Errorf("Delete(%q) failed: %v", name, err)
+		}
+	}
+}
+
+const (
+	// stressDirEnv makes the test binary run as one of the writer processes
+	// of TestEncryptedStorageConcurrentWriters.
+	stressDirEnv = "MF_TEST_STRESS_DIR"
+
+	stressProcesses  = 4
+	stressGoroutines = 4
+	stressRounds     = 10
+)
+
+func newStressStorage(dir string) *EncryptedStorage {
+	return &EncryptedStorage{configDir: dir, key: bytes.Repeat([]byte{0x42}, keySize)}
+}
+
+// runStressWriter increments the shared counter and adds its own accounts
+// from several goroutines, each with its own storage, like separate processes.
+func runStressWriter(t *testing.T, dir, id string) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			store := newStressStorage(dir)
+			for i := 0; i < stressRounds; i++ {
+				_, err := store.Update("COUNTER", func(account *types.Account) error {
+					account.Counter++
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Update failed: %v", err)
+					return
+				}
+
+				name := fmt.Sprintf("%s-%d-%d", id, g, i)
+				if err := store.Store(types.Account{Name: name, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+					t.Errorf("Store(%s) failed: %v", name, err)
+					return
+				}
+				if _, err := store.List(); err != nil {
+					t.Errorf("List failed: %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestStressWriterProcess(t *testing.T) {
+	dir := os.Getenv(stressDirEnv)
+	if dir == "" {
+		t.Skip("only run as a child of TestEncryptedStorageConcurrentWriters")
+	}
+	runStressWriter(t, dir, os.Getenv(stressDirEnv+"_ID"))
+}
+
+func TestEncryptedStorageConcurrentWriters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test")
+	}
+
+	dir := t.TempDir()
+	store := newStressStorage(dir)
+	if err := store.Store(types.Account{Name: "COUNTER", Secret: "JBSWY3DPEHPK3PXP", Type: types.AccountTypeHOTP}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	var cmds []*exec.Cmd
+	for p := 0; p < stressProcesses; p++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestStressWriterProcess$")
+		cmd.Env = append(os.Environ(), stressDirEnv+"="+dir, fmt.Sprintf("%s_ID=proc%d", stressDirEnv, p))
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start writer process: %v", err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	// This process writes alongside the children.
+	runStressWriter(t, dir, "parent")
+
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("Writer process failed: %v", err)
+		}
+	}
+
+	writers := stressProcesses + 1
+	account, err := store.Retrieve("COUNTER")
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if want := uint64(writers * stressGoroutines * stressRounds); account.Counter != want {
+		t.Errorf("Expected counter %d, got %d: updates were lost", want, account.Counter)
+	}
+
+	accounts, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if want := writers*stressGoroutines*stressRounds + 1; len(accounts) != want {
+		t.Errorf("Expected %d accounts, got %d: writes were lost", want, len(accounts))
+	}
+
+	if names := configFiles(t, dir); len(names) != 1 || names[0] != vaultFile {
+		t.Errorf("Expected only %s after the stress test, got %v", vaultFile, names)
+	}
+}
+
+// configFiles lists the configuration directory, leaving out the lock file.
+func configFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Name() != fsutil.LockFile {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+// sealLegacy encrypts data in the headerless format of mf 2.0.
+func sealLegacy(t *testing.T, material, data []byte) []byte {
+	t.Helper()
+	key := pbkdf2.Key(material, []byte(legacySalt), legacyIterations, keySize, sha256.New)
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatalf("newGCM failed: %v", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	return gcm.Seal(nonce, nonce, data, nil)
+}
@@ -0,0 +1,249 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Encrypted files start with a header that records how they were encrypted:
+//
+//	magic "MFEV" | version (1) | KDF id (1) | iterations (4) | memory KiB (4) |
+//	threads (1) | salt length (1) | salt | nonce length (1) | nonce | ciphertext
+//
+// Integers are big endian. The whole header is authenticated as AES-GCM
+// additional data, so it cannot be altered without failing decryption.
+// Files without the magic are the headerless nonce||ciphertext of mf 2.0.
+const (
+	envelopeMagic   = "MFEV"
+	envelopeVersion = 1
+
+	// kdfNone uses the key material directly as the AES-256 key.
+	kdfNone = 0
+	// kdfPBKDF2 derives the key with PBKDF2-HMAC-SHA256.
+	kdfPBKDF2 = 1
+	// kdfArgon2id derives the key from a passphrase with Argon2id.
+	kdfArgon2id = 2
+
+	pbkdf2Iterations = 600000
+
+	// Argon2id parameters for new passphrases (RFC 9106, second
+	// recommended option). Headers asking for more memory than
+	// argon2MaxMemory are rejected before deriving.
+	argon2Time      = 3
+	argon2Memory    = 64 * 1024
+	argon2Threads   = 4
+	argon2MaxMemory = 1024 * 1024
+	saltSize        = 16
+	keySize         = 32
+
+	// Parameters of the headerless mf 2.0 format.
+	legacySalt       = "mf-salt"
+	legacyIterations = 10000
+)
+
+// kdfParams identifies the key derivation of an envelope. Fields that do not
+// apply to a KDF are zero.
+type kdfParams struct {
+	ID         byte
+	Iterations uint32
+	Memory     uint32
+	Threads    uint8
+	Salt       []byte
+}
+
+func (p kdfParams) equal(other kdfParams) bool {
+	return p.ID == other.ID && p.Iterations == other.Iterations &&
+		p.Memory == other.Memory && p.Threads == other.Threads &&
+		bytes.Equal(p.Salt, other.Salt)
+}
+
+// deriveKey turns key material into an AES-256 key.
+func (p kdfParams) deriveKey(material []byte) ([]byte, error) {
+	switch p.ID {
+	case kdfNone:
+		if len(material) != keySize {
+			return nil, fmt.Errorf("raw key must be %d bytes", keySize)
+		}
+		return material, nil
+	case kdfPBKDF2:
+		if p.Iterations == 0 {
+			return nil, fmt.Errorf("invalid PBKDF2 iteration count")
+		}
+		return pbkdf2.Key(material, p.Salt, int(p.Iterations), keySize, sha256.New), nil
+	case kdfArgon2id:
+		if p.Iterations == 0 || p.Threads == 0 || p.Memory == 0 || p.Memory > argon2MaxMemory {
+			return nil, fmt.Errorf("invalid Argon2id parameters")
+		}
+		return argon2.IDKey(material, p.Salt, p.Iterations, p.Memory, p.Threads, keySize), nil
+	default:
+		return nil, fmt.Errorf("unsupported KDF id %d", p.ID)
+	}
+}
+
+// newPBKDF2Params returns the PBKDF2 parameters for new files, with a fresh
+// random salt.
+func newPBKDF2Params() (kdfParams, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return kdfParams{}, err
+	}
+	return kdfParams{ID: kdfPBKDF2, Iterations: pbkdf2Iterations, Salt: salt}, nil
+}
+
+// newArgon2idParams returns the Argon2id parameters for new passphrases, with
+// a fresh random salt.
+func newArgon2idParams() (kdfParams, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return kdfParams{}, err
+	}
+	return kdfParams{
+		ID:         kdfArgon2id,
+		Iterations: argon2Time,
+		Memory:     argon2Memory,
+		Threads:    argon2Threads,
+		Salt:       salt,
+	}, nil
+}
+
+type envelope struct {
+	Version    byte
+	KDF        kdfParams
+	Nonce      []byte
+	Ciphertext []byte
+	// header holds the raw header bytes authenticated as additional data.
+	header []byte
+}
+
+func isEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(envelopeMagic))
+}
+
+// sealEnvelope encrypts plaintext with key, which must have been derived with
+// kdf, and returns the complete envelope.
+func sealEnvelope(key []byte, kdf kdfParams, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	header := encodeHeader(envelopeVersion, kdf, nonce)
+	return gcm.Seal(header, nonce, plaintext, header), nil
+}
+
+// parseEnvelope splits an envelope into its header fields and ciphertext.
+func parseEnvelope(data []byte) (*envelope, error) {
+	r := bytes.NewReader(data)
+
+	magic := make([]byte, len(envelopeMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != envelopeMagic {
+		return nil, fmt.Errorf("not an mf encrypted file")
+	}
+
+	env := &envelope{}
+	var err error
+	if env.Version, err = r.ReadByte(); err != nil {
+		return nil, fmt.Errorf("truncated header")
+	}
+	switch env.Version {
+	case envelopeVersion:
+	default:
+		return nil, fmt.Errorf("unsupported format version %d", env.Version)
+	}
+
+	fields := []any{&env.KDF.ID, &env.KDF.Iterations, &env.KDF.Memory, &env.KDF.Threads}
+	for _, field := range fields {
+		if err := binary.Read(r, binary.BigEndian, field); err != nil {
+			return nil, fmt.Errorf("truncated header")
+		}
+	}
+	if env.KDF.Salt, err = readBlock(r); err != nil {
+		return nil, err
+	}
+	if env.Nonce, err = readBlock(r); err != nil {
+		return nil, err
+	}
+
+	headerLength := len(data) - r.Len()
+	env.header = data[:headerLength]
+	env.Ciphertext = data[headerLength:]
+	return env, nil
+}
+
+// open decrypts the envelope with a key derived according to its header.
+func (env *envelope) open(key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size")
+	}
+	return gcm.Open(nil, env.Nonce, env.Ciphertext, env.header)
+}
+
+func encodeHeader(version byte, kdf kdfParams, nonce []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(envelopeMagic)
+	buf.WriteByte(version)
+	buf.WriteByte(kdf.ID)
+	binary.Write(&buf, binary.BigEndian, kdf.Iterations)
+	binary.Write(&buf, binary.BigEndian, kdf.Memory)
+	buf.WriteByte(kdf.Threads)
+	buf.WriteByte(byte(len(kdf.Salt)))
+	buf.Write(kdf.Salt)
+	buf.WriteByte(byte(len(nonce)))
+	buf.Write(nonce)
+	return buf.Bytes()
+}
+
+func readBlock(r *bytes.Reader) ([]byte, error) {
+	length, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("truncated header")
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r, block); err != nil {
+		return nil, fmt.Errorf("truncated header")
+	}
+	return block, nil
+}
+
+// openLegacy decrypts the headerless nonce||ciphertext format of mf 2.0,
+// whose key is PBKDF2 of the key material with a constant salt.
+func openLegacy(material, data []byte) ([]byte, error) {
+	key := pbkdf2.Key(material, []byte(legacySalt), legacyIterations, keySize, sha256.New)
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,167 @@
+package secure
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mf/internal/types"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	kdf, err := newPBKDF2Params()
+	if err != nil {
+		t.Fatalf("newPBKDF2Params failed: %v", err)
+	}
+	key, err := kdf.deriveKey([]byte("material"))
+	if err != nil {
+		t.Fatalf("deriveKey failed: %v", err)
+	}
+
+	sealed, err := sealEnvelope(key, kdf, []byte("payload"))
+	if err != nil {
+		t.Fatalf("sealEnvelope failed: %v", err)
+	}
+
+	env, err := parseEnvelope(sealed)
+	if err != nil {
+		t.Fatalf("parseEnvelope failed: %v", err)
+	}
+	if env.Version != envelopeVersion {
+		t.Errorf("Expected version %d, got %d", envelopeVersion, env.Version)
+	}
+	if !env.KDF.equal(kdf) {
+		t.Errorf("Expected KDF parameters %+v, got %+v", kdf, env.KDF)
+	}
+	if len(env.KDF.Salt) != saltSize || bytes.Equal(env.KDF.Salt, []byte(legacySalt)) {
+		t.Errorf("Expected a random %d byte salt, got %x", saltSize, env.KDF.Salt)
+	}
+
+	derived, err := env.KDF.deriveKey([]byte("material"))
+	if err != nil {
+		t.Fatalf("deriveKey failed: %v", err)
+	}
+	plaintext, err := env.open(derived)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if string(plaintext) != "payload" {
+		t.Errorf("Expected payload, got %q", plaintext)
+	}
+}
+
+func TestVaultUsesDataKey(t *testing.T) {
+	store := newTestEncryptedStorage(t)
+
+	sealed, err := store.encrypt([]byte("payload"))
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	env, err := parseEnvelope(sealed)
+	if err != nil {
+		t.Fatalf("parseEnvelope failed: %v", err)
+	}
+	if env.KDF.ID != kdfNone {
+		t.Errorf("Expected the vault to be sealed with the raw data key, got KDF %d", env.KDF.ID)
+	}
+}
+
+func TestEnvelopeHeaderIsAuthenticated(t *testing.T) {
+	store := newTestEncryptedStorage(t)
+
+	sealed, err := store.encrypt([]byte("payload"))
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	// Flip a bit of the memory parameter, which the PBKDF2 key does not
+	// depend on: only the AAD check can catch it.
+	tampered := bytes.Clone(sealed)
+	tampered[len(envelopeMagic)+2+4] ^= 0x01
+
+	if _, err := store.decrypt(tampered); err == nil {
+		t.Error("Expected decryption of a tampered header to fail")
+	}
+}
+
+func TestEnvelopeRejectsUnknownVersion(t *testing.T) {
+	store := newTestEncryptedStorage(t)
+
+	sealed, err := store.encrypt([]byte("payload"))
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	sealed[len(envelopeMagic)] = envelopeVersion + 1
+
+	if _, err := store.decrypt(sealed); err == nil {
+		t.Error("Expected unknown format version to be rejected")
+	}
+}
+
+func TestEnvelopeRejectsTruncatedHeader(t *testing.T) {
+	store := newTestEncryptedStorage(t)
+
+	sealed, err := store.encrypt([]byte("payload"))
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	for _, length := range []int{len(envelopeMagic), len(envelopeMagic) + 3, len(envelopeMagic) + 12} {
+		if _, err := store.decrypt(sealed[:length]); err == nil {
+			t.Errorf("Expected header truncated to %d bytes to be rejected", length)
+		}
+	}
+}
+
+func TestLegacyVaultsUpgradedOnLoad(t *testing.T) {
+	legacy := vault{
+		Version:  vaultVersion,
+		Accounts: map[string]types.Account{"old": {Name: "old", Secret: "JBSWY3DPEHPK3PXP"}},
+	}
+	data, _ := json.Marshal(legacy)
+
+	formats := map[string]func(store *EncryptedStorage) []byte{
+		// mf 2.0: headerless, constant salt.
+		"headerless": func(store *EncryptedStorage) []byte {
+			return sealLegacy(t, store.legacyMaterial, data)
+		},
+		// Envelope keyed with PBKDF2 of the machine key.
+		"machine key envelope": func(store *EncryptedStorage) []byte {
+			kdf, err := newPBKDF2Params()
+			if err != nil {
+				t.Fatalf("newPBKDF2Params failed: %v", err)
+			}
+			key, _ := kdf.deriveKey(store.legacyMaterial)
+			sealed, err := sealEnvelope(key, kdf, data)
+			if err != nil {
+				t.Fatalf("sealEnvelope failed: %v", err)
+			}
+			return sealed
+		},
+	}
+
+	for name, seal := range formats {
+		store := newTestEncryptedStorage(t)
+		path := filepath.Join(store.configDir, vaultFile)
+		writeTestFile(t, path, seal(store))
+
+		if _, err := store.Retrieve("old"); err != nil {
+			t.Fatalf("%s: Retrieve failed: %v", name, err)
+		}
+
+		upgraded, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: ReadFile failed: %v", name, err)
+		}
+		env, err := parseEnvelope(upgraded)
+		if err != nil {
+			t.Fatalf("%s: vault not rewritten as an envelope: %v", name, err)
+		}
+		if env.KDF.ID != kdfNone {
+			t.Errorf("%s: expected vault to be re-encrypted with the data key", name)
+		}
+	}
+}
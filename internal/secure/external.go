@@ -0,0 +1,95 @@
+package secure
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// KeyFileEnv is the environment variable naming a file whose content is the
+// vault key-encryption key.
+const KeyFileEnv = "MF_KEY_FILE"
+
+// KeyFile names a file whose content is the vault key-encryption key. It
+// takes precedence over KeyFileEnv; the command line sets it from --key-file.
+var KeyFile string
+
+func keyFilePath() string {
+	if KeyFile != "" {
+		return KeyFile
+	}
+	return os.Getenv(KeyFileEnv)
+}
+
+// External key sources keep the key outside the configuration directory, so
+// the vault can be copied to any host that has the same key file or can run
+// the same command. Their output is stretched with PBKDF2 like the machine
+// identity, as it may be a password rather than random bytes. A vault key
+// still wrapped with the machine identity is only switched to them by
+// mf recover --adopt.
+
+// fileKeySource reads the key from a file.
+type fileKeySource struct {
+	path string
+}
+
+func (s fileKeySource) material(bool) ([]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return externalKey(data, "key file")
+}
+
+func (fileKeySource) newKDF() (kdfParams, error) {
+	return newPBKDF2Params()
+}
+
+func (fileKeySource) adoptedMaterial() ([]byte, error) {
+	return MachineIdentity()
+}
+
+// commandKeySource runs a shell command, such as a password manager, and
+// reads the key from its standard output. Standard input and error are those
+// of mf, so the command can prompt.
+type commandKeySource struct {
+	command string
+}
+
+func (s commandKeySource) material(bool) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", s.command)
+	} else {
+		cmd = exec.Command("sh", "-c", s.command)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("key command failed: %w", err)
+	}
+	return externalKey(out, "key command output")
+}
+
+func (commandKeySource) newKDF() (kdfParams, error) {
+	return newPBKDF2Params()
+}
+
+func (commandKeySource) adoptedMaterial() ([]byte, error) {
+	return MachineIdentity()
+}
+
+// externalKey strips the trailing newline editors and commands add, and
+// rejects an empty key.
+func externalKey(data []byte, what string) ([]byte, error) {
+	key := bytes.TrimRight(data, "\r\n")
+	if len(strings.TrimSpace(string(key))) == 0 {
+		return nil, fmt.Errorf("%s is empty", what)
+	}
+	return key, nil
+}
@@ -0,0 +1,163 @@
+package secure
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"mf/internal/config"
+)
+
+func TestNewKeySourcePrecedence(t *testing.T) {
+	t.Setenv(KeyFileEnv, "")
+	cfg := &config.Config{KeySource: KeySourceKeyring, KeyCommand: "pass show mf"}
+
+	source, err := newKeySource(cfg)
+	if err != nil {
+		t.Fatalf("newKeySource failed: %v", err)
+	}
+	if _, ok := source.(commandKeySource); !ok {
+		t.Errorf("Expected key_command to override key_source, got %T", source)
+	}
+
+	t.Setenv(KeyFileEnv, "/from/env")
+	source, _ = newKeySource(cfg)
+	if s, ok := source.(fileKeySource); !ok || s.path != "/from/env" {
+		t.Errorf("Expected %s to override key_command, got %#v", KeyFileEnv, source)
+	}
+
+	KeyFile = "/from/flag"
+	defer func() { KeyFile = "" }()
+	source, _ = newKeySource(cfg)
+	if s, ok := source.(fileKeySource); !ok || s.path != "/from/flag" {
+		t.Errorf("Expected KeyFile to override %s, got %#v", KeyFileEnv, source)
+	}
+}
+
+func TestFileKeySource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mf.key")
+	writeTestFile(t, path, []byte("shared secret\r\n"))
+
+	material, err := fileKeySource{path: path}.material(false)
+	if err != nil {
+		t.Fatalf("material failed: %v", err)
+	}
+	if string(material) != "shared secret" {
+		t.Errorf("Expected trailing newline to be stripped, got %q", material)
+	}
+
+	empty := filepath.Join(dir, "empty.key")
+	writeTestFile(t, empty, []byte("\n"))
+	if _, err := (fileKeySource{path: empty}).material(false); err == nil {
+		t.Error("Expected an empty key file to be rejected")
+	}
+	if _, err := (fileKeySource{path: filepath.Join(dir, "missing")}).material(false); err == nil {
+		t.Error("Expected a missing key file to fail")
+	}
+}
+
+func TestCommandKeySource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	material, err := commandKeySource{command: "printf 'from command\\n'"}.material(false)
+	if err != nil {
+		t.Fatalf("material failed: %v", err)
+	}
+	if string(material) != "from command" {
+		t.Errorf("Expected command output, got %q", material)
+	}
+
+	if _, err := (commandKeySource{command: "exit 3"}).material(false); err == nil {
+		t.Error("Expected a failing key command to fail")
+	}
+	if _, err := (commandKeySource{command: "true"}).material(false); err == nil {
+		t.Error("Expected empty command output to be rejected")
+	}
+}
+
+func TestKeyFileMakesVaultPortable(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "mf.key")
+	writeTestFile(t, keyPath, []byte("shared secret"))
+	source := fileKeySource{path: keyPath}
+
+	first := t.TempDir()
+	dataKey, err := loadDataKey(first, source)
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+
+	// Another host with the same key file but a different machine identity.
+	second := t.TempDir()
+	wrapped, err := os.ReadFile(filepath.Join(first, vaultKeyFile))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	writeTestFile(t, filepath.Join(second, vaultKeyFile), wrapped)
+
+	copied, err := unwrapDataKey(wrapped, source)
+	if err != nil {
+		t.Fatalf("unwrapDataKey on the second host failed: %v", err)
+	}
+	if !bytes.Equal(copied, dataKey) {
+		t.Error("Expected the same data key on both hosts")
+	}
+
+	if _, err := unwrapDataKey(wrapped, staticKeySource("some machine")); err == nil {
+		t.Error("Expected the machine identity not to unwrap a key-file vault")
+	}
+}
+
+func TestKeyFileRequiresAdoption(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(KeyFileEnv, "")
+	t.Setenv(PassphraseEnv, "")
+
+	configDir, err := config.Dir()
+	if err != nil {
+		t.Fatalf("config.Dir failed: %v", err)
+	}
+	original, err := loadDataKey(configDir, machineKeySource{})
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	before, err := os.ReadFile(filepath.Join(configDir, vaultKeyFile))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	keyPath := filepath.Join(t.TempDir(), "mf.key")
+	writeTestFile(t, keyPath, []byte("shared secret"))
+	source := fileKeySource{path: keyPath}
+
+	// A key file alone, e.g. from a stale MF_KEY_FILE, must not re-wrap the
+	// vault key.
+	if _, err := loadDataKey(configDir, source); err == nil || !strings.Contains(err.Error(), "mf recover --adopt") {
+		t.Fatalf("Expected loadDataKey to point to mf recover --adopt, got %v", err)
+	}
+	if after, _ := os.ReadFile(filepath.Join(configDir, vaultKeyFile)); !bytes.Equal(after, before) {
+		t.Fatal("vault.key should not change without adoption")
+	}
+
+	KeyFile = keyPath
+	defer func() { KeyFile = "" }()
+	if err := AdoptKeySource(); err != nil {
+		t.Fatalf("AdoptKeySource failed: %v", err)
+	}
+
+	adopted, err := loadDataKey(configDir, source)
+	if err != nil {
+		t.Fatalf("loadDataKey after adoption failed: %v", err)
+	}
+	if !bytes.Equal(adopted, original) {
+		t.Error("Expected the existing data key to be kept")
+	}
+	if err := AdoptKeySource(); err != nil {
+		t.Errorf("AdoptKeySource should accept a vault already protected by the key file: %v", err)
+	}
+}
@@ -4,31 +4,98 @@ import (
 	"crypto/md5"
 	"fmt"
 	"os"
+	"os/exec"
 	"os/user"
+	"regexp"
 	"runtime"
 	"strings"
 )
 
-func GetMachineKey() ([]byte, error) {
-	var identifiers []string
+// machineIDFiles are the Linux locations of the machine ID, which unlike the
+// hostname survives renames.
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
 
-	hostname, err := os.Hostname()
-	if err == nil {
-		identifiers = append(identifiers, hostname)
+// MachineIdentity returns a stable identifier of this machine and user. It
+// is the input of the key that wraps the vault key and is never used as a
+// key directly. When the OS has no machine ID it falls back to the hostname,
+// so renaming the host, as containers without /etc/machine-id do on every
+// restart, locks the vault until mf recover is run.
+func MachineIdentity() ([]byte, error) {
+	id := machineID()
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to gather machine identifiers: %w", err)
+		}
+		return hostnameIdentity(hostname), nil
 	}
 
-	currentUser, err := user.Current()
-	if err == nil {
+	return []byte(strings.Join(append([]string{"machine-id:" + id}, userIdentifiers()...), "|")), nil
+}
+
+// usesHostnameIdentity reports whether MachineIdentity falls back to the
+// hostname because the OS has no machine ID.
+func usesHostnameIdentity() bool {
+	return machineID() == ""
+}
+
+// hostnameIdentity is the identity used before machine IDs were read: the
+// hostname followed by the user and platform. It is kept to unlock vaults
+// wrapped with it, and to recover after a hostname change.
+func hostnameIdentity(hostname string) []byte {
+	identifiers := append([]string{hostname}, userIdentifiers()...)
+	return []byte(strings.Join(identifiers, "|"))
+}
+
+func userIdentifiers() []string {
+	var identifiers []string
+	if currentUser, err := user.Current(); err == nil {
 		identifiers = append(identifiers, currentUser.Uid, currentUser.Username)
 	}
+	return append(identifiers, runtime.GOOS, runtime.GOARCH)
+}
 
-	identifiers = append(identifiers, runtime.GOOS, runtime.GOARCH)
+// machineID returns the OS machine identifier, or "" if none is available.
+func machineID() string {
+	switch runtime.GOOS {
+	case "linux":
+		for _, path := range machineIDFiles {
+			if data, err := os.ReadFile(path); err == nil {
+				if id := strings.TrimSpace(string(data)); id != "" {
+					return id
+				}
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+		if err == nil {
+			if m := regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`).FindSubmatch(out); m != nil {
+				return string(m[1])
+			}
+		}
+	case "windows":
+		out, err := exec.Command("reg", "query", `HKLM\SOFTWARE\Microsoft\Cryptography`, "/v", "MachineGuid").Output()
+		if err == nil {
+			fields := strings.Fields(string(out))
+			if len(fields) > 0 && strings.Contains(string(out), "MachineGuid") {
+				return fields[len(fields)-1]
+			}
+		}
+	}
+	return ""
+}
 
-	if len(identifiers) == 0 {
-		return nil, fmt.Errorf("failed to gather machine identifiers")
+// GetMachineKey returns the key material used by mf 2.0, an MD5 of the
+// hostname identity. It is only used to read files written by those versions.
+func GetMachineKey() ([]byte, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather machine identifiers: %w", err)
 	}
+	return legacyMachineKey(hostname), nil
+}
 
-	combined := strings.Join(identifiers, "|")
-	hash := md5.Sum([]byte(combined))
-	return hash[:], nil
+func legacyMachineKey(hostname string) []byte {
+	hash := md5.Sum(hostnameIdentity(hostname))
+	return hash[:]
 }
@@ -1,6 +1,11 @@
 package secure
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -23,3 +28,104 @@ func TestGetMachineKey(t *testing.T) {
 		t.Error("Machine key should be consistent between calls")
 	}
 }
+
+func TestMachineIdentityPrefersMachineID(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("machine ID files are only read on Linux")
+	}
+
+	path := filepath.Join(t.TempDir(), "machine-id")
+	if err := os.WriteFile(path, []byte("0123456789abcdef\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	saved := machineIDFiles
+	machineIDFiles = []string{filepath.Join(t.TempDir(), "missing"), path}
+	defer func() { machineIDFiles = saved }()
+
+	identity, err := MachineIdentity()
+	if err != nil {
+		t.Fatalf("MachineIdentity failed: %v", err)
+	}
+	if !strings.HasPrefix(string(identity), "machine-id:0123456789abcdef|") {
+		t.Errorf("Expected identity based on the machine ID, got %q", identity)
+	}
+
+	hostname, _ := os.Hostname()
+	if strings.Contains(string(identity), hostname+"|") {
+		t.Error("Identity should not depend on the hostname when a machine ID exists")
+	}
+}
+
+func TestLoadDataKeyRewrapsHostnameIdentity(t *testing.T) {
+	dir := t.TempDir()
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("Hostname failed: %v", err)
+	}
+
+	original, err := loadDataKey(dir, staticKeySource(hostnameIdentity(hostname)))
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "machine-id")
+	if err := os.WriteFile(path, []byte("fedcba9876543210\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	saved := machineIDFiles
+	machineIDFiles = []string{path}
+	defer func() { machineIDFiles = saved }()
+
+	key, err := loadDataKey(dir, machineKeySource{})
+	if err != nil {
+		t.Fatalf("loadDataKey with machine source failed: %v", err)
+	}
+	if !bytes.Equal(key, original) {
+		t.Fatal("Expected the data key wrapped with the hostname identity")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, vaultKeyFile))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	identity, err := MachineIdentity()
+	if err != nil {
+		t.Fatalf("MachineIdentity failed: %v", err)
+	}
+	if _, err := unwrapDataKeyWith(data, identity); err != nil {
+		t.Errorf("Expected vault key to be re-wrapped with the machine identity: %v", err)
+	}
+}
+
+func TestRecoveryKeyIssuedWarnsAboutHostnameIdentity(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("machine ID files are only read on Linux")
+	}
+
+	saved, savedHook := machineIDFiles, RecoveryKeyIssued
+	defer func() { machineIDFiles, RecoveryKeyIssued = saved, savedHook }()
+
+	var issued, warned bool
+	RecoveryKeyIssued = func(recoveryKey string, hostnameIdentity bool) {
+		issued, warned = true, hostnameIdentity
+	}
+
+	machineIDFiles = []string{filepath.Join(t.TempDir(), "missing")}
+	if _, err := loadDataKey(t.TempDir(), machineKeySource{}); err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	if !issued || !warned {
+		t.Error("Expected a hostname-based identity to be reported at first run")
+	}
+
+	path := filepath.Join(t.TempDir(), "machine-id")
+	writeTestFile(t, path, []byte("0123456789abcdef\n"))
+	machineIDFiles = []string{path}
+	issued, warned = false, false
+	if _, err := loadDataKey(t.TempDir(), machineKeySource{}); err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	if !issued || warned {
+		t.Error("Expected no warning when a machine ID exists")
+	}
+}
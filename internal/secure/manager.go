@@ -3,11 +3,13 @@ package secure
 import (
 	"fmt"
 	"mf/internal/types"
+	"sync"
 )
 
 type Manager struct {
 	primary   SecureStorage
 	secondary SecureStorage
+	mu        sync.Mutex
 }
 
 func NewManager() (*Manager, error) {
@@ -48,6 +50,34 @@ func (m *Manager) Store(account types.Account) error {
 	return err
 }
 
+// batchStorer is implemented by backends that can store several accounts in
+// a single write.
+type batchStorer interface {
+	StoreAll(accounts []types.Account) error
+}
+
+// StoreAll stores a batch of accounts. If the primary backend fails, the
+// whole batch is stored in the secondary one.
+func (m *Manager) StoreAll(accounts []types.Account) error {
+	err := storeAll(m.primary, accounts)
+	if err != nil && m.secondary != nil {
+		return storeAll(m.secondary, accounts)
+	}
+	return err
+}
+
+func storeAll(backend SecureStorage, accounts []types.Account) error {
+	if b, ok := backend.(batchStorer); ok {
+		return b.StoreAll(accounts)
+	}
+	for _, account := range accounts {
+		if err := backend.Store(account); err != nil {
+			return fmt.Errorf("failed to save account '%s': %w", account.Name, err)
+		}
+	}
+	return nil
+}
+
 func (m *Manager) Retrieve(name string) (*types.Account, error) {
 	account, err := m.primary.Retrieve(name)
 	if err != nil && m.secondary != nil {
@@ -71,3 +101,42 @@ func (m *Manager) Delete(name string) error {
 	}
 	return err
 }
+
+// updater is implemented by backends that can update an account atomically
+// with respect to other processes.
+type updater interface {
+	Update(name string, fn func(account *types.Account) error) (*types.Account, error)
+}
+
+// Update loads an account, applies fn to it and stores the result back in the
+// backend it was loaded from. The account is only written if fn succeeds.
+func (m *Manager) Update(name string, fn func(account *types.Account) error) (*types.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	backend := m.primary
+	account, err := m.primary.Retrieve(name)
+	if err != nil && m.secondary != nil {
+		backend = m.secondary
+		account, err = m.secondary.Retrieve(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-read under the backend's lock so the update applies to the latest
+	// copy, not the one read above.
+	if u, ok := backend.(updater); ok {
+		return u.Update(name, fn)
+	}
+
+	if err := fn(account); err != nil {
+		return nil, err
+	}
+
+	if err := backend.Store(*account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
@@ -0,0 +1,137 @@
+package secure
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"mf/internal/types"
+)
+
+type memoryStorage struct {
+	mu       sync.Mutex
+	accounts map[string]types.Account
+	failAll  bool
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{accounts: make(map[string]types.Account)}
+}
+
+func (m *memoryStorage) Store(account types.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failAll {
+		return fmt.Errorf("storage unavailable")
+	}
+	m.accounts[account.Name] = account
+	return nil
+}
+
+func (m *memoryStorage) Retrieve(name string) (*types.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	account, ok := m.accounts[name]
+	if !ok || m.failAll {
+		return nil, fmt.Errorf("account '%s' not found", name)
+	}
+	return &account, nil
+}
+
+func (m *memoryStorage) List() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var names []string
+	for name := range m.accounts {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (m *memoryStorage) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[name]; !ok {
+		return fmt.Errorf("account '%s' not found", name)
+	}
+	delete(m.accounts, name)
+	return nil
+}
+
+func TestManagerUpdateIncrementsCounter(t *testing.T) {
+	primary := newMemoryStorage()
+	manager := &Manager{primary: primary}
+
+	primary.Store(types.Account{Name: "vpn", Secret: "JBSWY3DPEHPK3PXP", Type: types.AccountTypeHOTP})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := manager.Update("vpn", func(account *types.Account) error {
+				account.Counter++
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	account, err := manager.Retrieve("vpn")
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if account.Counter != 50 {
+		t.Errorf("Expected counter 50, got %d", account.Counter)
+	}
+}
+
+func TestManagerUpdateUsesBackendHoldingAccount(t *testing.T) {
+	primary := newMemoryStorage()
+	secondary := newMemoryStorage()
+	manager := &Manager{primary: primary, secondary: secondary}
+
+	secondary.Store(types.Account{Name: "legacy", Secret: "JBSWY3DPEHPK3PXP", Counter: 7})
+
+	if _, err := manager.Update("legacy", func(account *types.Account) error {
+		account.Counter++
+		return nil
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if _, err := primary.Retrieve("legacy"); err == nil {
+		t.Error("Account should not have been copied to the primary backend")
+	}
+
+	account, err := secondary.Retrieve("legacy")
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if account.Counter != 8 {
+		t.Errorf("Expected counter 8, got %d", account.Counter)
+	}
+}
+
+func TestManagerUpdateDoesNotStoreOnError(t *testing.T) {
+	primary := newMemoryStorage()
+	manager := &Manager{primary: primary}
+
+	primary.Store(types.Account{Name: "vpn", Secret: "JBSWY3DPEHPK3PXP", Counter: 3})
+
+	_, err := manager.Update("vpn", func(account *types.Account) error {
+		account.Counter = 100
+		return fmt.Errorf("generation failed")
+	})
+	if err == nil {
+		t.Fatal("Expected Update to return the callback error")
+	}
+
+	account, _ := primary.Retrieve("vpn")
+	if account.Counter != 3 {
+		t.Errorf("Expected counter to stay at 3, got %d", account.Counter)
+	}
+}
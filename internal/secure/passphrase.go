@@ -0,0 +1,138 @@
+package secure
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"mf/internal/config"
+)
+
+// PassphraseEnv is the environment variable holding the vault passphrase.
+const PassphraseEnv = "MF_PASSPHRASE"
+
+// ErrPassphraseRequired is returned when the vault key is protected by a
+// passphrase and none could be obtained.
+var ErrPassphraseRequired = errors.New("vault is protected by a passphrase")
+
+// PassphraseSource obtains the current vault passphrase when the vault key
+// is protected by one. When nil, only PassphraseEnv is consulted. The command
+// line sets it to also honour --passphrase-file and prompt on a terminal.
+var PassphraseSource func() ([]byte, error)
+
+func readPassphrase() ([]byte, error) {
+	if PassphraseSource != nil {
+		return PassphraseSource()
+	}
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	return nil, ErrPassphraseRequired
+}
+
+// passphraseKeySource derives the KEK from a passphrase with Argon2id. A nil
+// passphrase is read with readPassphrase when needed.
+type passphraseKeySource struct {
+	passphrase []byte
+}
+
+func (s passphraseKeySource) material(bool) ([]byte, error) {
+	if s.passphrase != nil {
+		return s.passphrase, nil
+	}
+	return readPassphrase()
+}
+
+func (passphraseKeySource) newKDF() (kdfParams, error) {
+	return newArgon2idParams()
+}
+
+// HasPassphrase reports whether the vault key is protected by a passphrase.
+func HasPassphrase() (bool, error) {
+	configDir, err := config.Dir()
+	if err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, vaultKeyFile))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read vault key: %w", err)
+	}
+
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return false, fmt.Errorf("invalid vault key file: %w", err)
+	}
+	return env.KDF.ID == kdfArgon2id, nil
+}
+
+// SetPassphrase re-wraps the vault key with a new passphrase. If the vault
+// key is already protected by a passphrase, the current one is read through
+// PassphraseSource to unlock it. The vault itself is not re-encrypted.
+func SetPassphrase(passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return fmt.Errorf("passphrase must not be empty")
+	}
+
+	configDir, source, err := currentKeySource()
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := loadDataKey(configDir, source)
+	if err != nil {
+		return err
+	}
+
+	return rewrapDataKey(configDir, passphraseKeySource{passphrase: passphrase}, dataKey)
+}
+
+// RemovePassphrase re-wraps the vault key with the configured key source,
+// after unlocking it with the current passphrase.
+func RemovePassphrase() error {
+	configDir, source, err := currentKeySource()
+	if err != nil {
+		return err
+	}
+
+	dataKey, err := loadDataKey(configDir, source)
+	if err != nil {
+		return err
+	}
+
+	return rewrapDataKey(configDir, source, dataKey)
+}
+
+// rewrapDataKey wraps the data key with source outside the lock, as the KDF
+// may be slow, and replaces vault.key under it.
+func rewrapDataKey(configDir string, source keySource, dataKey []byte) error {
+	wrapped, err := wrapDataKey(source, dataKey)
+	if err != nil {
+		return err
+	}
+	return withLock(configDir, func() error {
+		return writeDataKey(configDir, wrapped)
+	})
+}
+
+func currentKeySource() (string, keySource, error) {
+	configDir, err := config.Dir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return "", nil, err
+	}
+
+	source, err := newKeySource(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	return configDir, source, nil
+}
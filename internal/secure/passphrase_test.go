@@ -0,0 +1,85 @@
+package secure
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPassphraseSetChangeRemove(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "")
+
+	configDir := filepath.Join(os.Getenv("HOME"), ".config", "mf")
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	dataKey, err := loadDataKey(configDir, machineKeySource{})
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+
+	if protected, _ := HasPassphrase(); protected {
+		t.Fatal("New vault key should not be protected by a passphrase")
+	}
+
+	if err := SetPassphrase([]byte("correct horse")); err != nil {
+		t.Fatalf("SetPassphrase failed: %v", err)
+	}
+	if protected, _ := HasPassphrase(); !protected {
+		t.Fatal("Vault key should be protected after SetPassphrase")
+	}
+
+	if _, err := loadDataKey(configDir, machineKeySource{}); !errors.Is(err, ErrPassphraseRequired) {
+		t.Fatalf("Expected ErrPassphraseRequired without a passphrase, got %v", err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := loadDataKey(configDir, machineKeySource{}); err == nil {
+		t.Fatal("Expected a wrong passphrase to fail")
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	unlocked, err := loadDataKey(configDir, machineKeySource{})
+	if err != nil {
+		t.Fatalf("loadDataKey with passphrase failed: %v", err)
+	}
+	if !bytes.Equal(unlocked, dataKey) {
+		t.Fatal("Passphrase should unwrap the same data key")
+	}
+
+	// Changing the passphrase keeps the data key.
+	if err := SetPassphrase([]byte("battery staple")); err != nil {
+		t.Fatalf("SetPassphrase (change) failed: %v", err)
+	}
+	t.Setenv(PassphraseEnv, "battery staple")
+	if unlocked, err = loadDataKey(configDir, machineKeySource{}); err != nil || !bytes.Equal(unlocked, dataKey) {
+		t.Fatalf("Expected new passphrase to unwrap the data key, got %v", err)
+	}
+
+	if err := RemovePassphrase(); err != nil {
+		t.Fatalf("RemovePassphrase failed: %v", err)
+	}
+	t.Setenv(PassphraseEnv, "")
+	if unlocked, err = loadDataKey(configDir, machineKeySource{}); err != nil || !bytes.Equal(unlocked, dataKey) {
+		t.Fatalf("Expected machine key to unwrap the data key after removal, got %v", err)
+	}
+}
+
+func TestSetPassphraseRejectsEmpty(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := SetPassphrase(nil); err == nil {
+		t.Error("Expected empty passphrase to be rejected")
+	}
+}
+
+func TestArgon2idHeaderLimits(t *testing.T) {
+	params := kdfParams{ID: kdfArgon2id, Iterations: 1, Memory: argon2MaxMemory + 1, Threads: 1, Salt: []byte("salt")}
+	if _, err := params.deriveKey([]byte("passphrase")); err == nil {
+		t.Error("Expected excessive Argon2id memory to be rejected")
+	}
+}
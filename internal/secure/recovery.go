@@ -0,0 +1,162 @@
+package secure
+
+import (
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// The recovery key is the vault data key itself, printed in groups of
+// base32 characters. It opens the vault regardless of how vault.key is
+// wrapped, so it stays valid across passphrase and key source changes.
+const recoveryGroupSize = 4
+
+// RecoveryKeyIssued is called with the printable recovery key when a new
+// vault key is generated. hostnameIdentity is set when the key is wrapped
+// with the machine identity and that identity is the hostname fallback. The
+// command line sets it to show the key once and warn about the fallback.
+var RecoveryKeyIssued func(recoveryKey string, hostnameIdentity bool)
+
+// FormatRecoveryKey encodes a data key as a printable recovery key.
+func FormatRecoveryKey(dataKey []byte) string {
+	encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(dataKey)
+
+	var groups []string
+	for len(encoded) > recoveryGroupSize {
+		groups = append(groups, encoded[:recoveryGroupSize])
+		encoded = encoded[recoveryGroupSize:]
+	}
+	return strings.Join(append(groups, encoded), "-")
+}
+
+// ParseRecoveryKey decodes a recovery key, ignoring case, spaces and dashes.
+func ParseRecoveryKey(recoveryKey string) ([]byte, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(recoveryKey)))
+
+	dataKey, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(cleaned)
+	if err != nil || len(dataKey) != keySize {
+		return nil, fmt.Errorf("invalid recovery key")
+	}
+	return dataKey, nil
+}
+
+// RecoveryKey returns the printable recovery key of the current vault.
+func RecoveryKey() (string, error) {
+	configDir, source, err := currentKeySource()
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := loadDataKey(configDir, source)
+	if err != nil {
+		return "", err
+	}
+	return FormatRecoveryKey(dataKey), nil
+}
+
+// RecoverWithKey re-wraps the vault key with the configured key source after
+// checking that the recovery key opens the vault. Any passphrase is removed.
+func RecoverWithKey(recoveryKey string) error {
+	dataKey, err := ParseRecoveryKey(recoveryKey)
+	if err != nil {
+		return err
+	}
+
+	legacyMaterial, err := GetMachineKey()
+	if err != nil {
+		return err
+	}
+
+	configDir, source, err := currentKeySource()
+	if err != nil {
+		return err
+	}
+
+	return rekey(configDir, source, dataKey, legacyMaterial)
+}
+
+// RecoverWithHostname re-wraps the vault key with the configured key source,
+// unlocking it with the identity of this machine under its old hostname.
+// Files written by mf 2.0 under that hostname are read as well.
+func RecoverWithHostname(hostname string) error {
+	if hostname == "" {
+		return fmt.Errorf("hostname must not be empty")
+	}
+
+	configDir, source, err := currentKeySource()
+	if err != nil {
+		return err
+	}
+
+	// The vault key may have been wrapped under the old hostname, or already
+	// under the current identity while mf 2.0 files under the old hostname
+	// were left unreadable.
+	var dataKey []byte
+	data, err := readDataKey(configDir)
+	if err == nil && data != nil {
+		dataKey, err = unwrapDataKeyWith(data, hostnameIdentity(hostname))
+	}
+	if err != nil || dataKey == nil {
+		if dataKey, err = loadDataKey(configDir, source); err != nil {
+			return fmt.Errorf("vault key was not wrapped on this machine as %q", hostname)
+		}
+	}
+
+	return rekey(configDir, source, dataKey, legacyMachineKey(hostname))
+}
+
+// AdoptKeySource re-wraps a vault key still protected by the machine identity
+// with the configured key file, key command or keyring key. A vault key that
+// already opens with it is left as is.
+func AdoptKeySource() error {
+	configDir, source, err := currentKeySource()
+	if err != nil {
+		return err
+	}
+
+	adopting, ok := source.(adoptingKeySource)
+	if !ok {
+		return fmt.Errorf("no key file, key command or keyring key source is configured")
+	}
+
+	data, err := readDataKey(configDir)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return fmt.Errorf("vault key not found")
+	}
+	if isPassphraseProtected(data) {
+		return fmt.Errorf("vault key is protected by a passphrase: remove it with mf passphrase remove first")
+	}
+	if _, err := unwrapDataKey(data, source); err == nil {
+		return nil
+	}
+
+	_, err = lockedLoadDataKey(configDir, source, data, adopting.adoptedMaterial,
+		fmt.Errorf("vault key is not protected by the machine identity of this host (use mf recover --recovery-key)"))
+	return err
+}
+
+// rekey checks that dataKey opens the vault, upgrading any legacy files with
+// legacyMaterial, and then wraps it with source. The key is wrapped before
+// the lock is taken, as the KDF may be slow.
+func rekey(configDir string, source keySource, dataKey, legacyMaterial []byte) error {
+	wrapped, err := wrapDataKey(source, dataKey)
+	if err != nil {
+		return err
+	}
+
+	return withLock(configDir, func() error {
+		e := &EncryptedStorage{configDir: configDir, key: dataKey, legacyMaterial: legacyMaterial}
+		if _, err := e.load(); err != nil {
+			return fmt.Errorf("recovery failed: %w", err)
+		}
+		return writeDataKey(configDir, wrapped)
+	})
+}
@@ -0,0 +1,138 @@
+package secure
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mf/internal/config"
+	"mf/internal/types"
+)
+
+func TestRecoveryKeyRoundTrip(t *testing.T) {
+	dataKey := bytes.Repeat([]byte{0xA5}, keySize)
+
+	recoveryKey := FormatRecoveryKey(dataKey)
+	for _, group := range strings.Split(recoveryKey, "-") {
+		if len(group) > recoveryGroupSize {
+			t.Fatalf("Unexpected group %q in recovery key %q", group, recoveryKey)
+		}
+	}
+
+	for _, input := range []string{
+		recoveryKey,
+		strings.ToLower(recoveryKey),
+		" " + strings.ReplaceAll(recoveryKey, "-", " ") + "\n",
+	} {
+		parsed, err := ParseRecoveryKey(input)
+		if err != nil {
+			t.Fatalf("ParseRecoveryKey(%q) failed: %v", input, err)
+		}
+		if !bytes.Equal(parsed, dataKey) {
+			t.Errorf("ParseRecoveryKey(%q) returned a different key", input)
+		}
+	}
+
+	for _, invalid := range []string{"", "ABCD-EFGH", recoveryKey + "-ABCD", "1111-" + recoveryKey[5:]} {
+		if _, err := ParseRecoveryKey(invalid); err == nil {
+			t.Errorf("Expected ParseRecoveryKey(%q) to fail", invalid)
+		}
+	}
+}
+
+// newRecoveryTestVault creates a vault under a temporary HOME whose key was
+// wrapped with material this machine no longer produces.
+func newRecoveryTestVault(t *testing.T, lostMaterial []byte) (string, []byte) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "")
+
+	configDir, err := config.Dir()
+	if err != nil {
+		t.Fatalf("config.Dir failed: %v", err)
+	}
+
+	dataKey, err := loadDataKey(configDir, staticKeySource(lostMaterial))
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	storage := &EncryptedStorage{configDir: configDir, key: dataKey}
+	if err := storage.Store(types.Account{Name: "GITHUB", Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	return configDir, dataKey
+}
+
+func assertVaultUnlocks(t *testing.T, configDir string) {
+	t.Helper()
+	dataKey, err := loadDataKey(configDir, machineKeySource{})
+	if err != nil {
+		t.Fatalf("Vault key should unlock with this machine after recovery: %v", err)
+	}
+	storage := &EncryptedStorage{configDir: configDir, key: dataKey}
+	if _, err := storage.Retrieve("GITHUB"); err != nil {
+		t.Errorf("Retrieve after recovery failed: %v", err)
+	}
+}
+
+func TestRecoverWithKey(t *testing.T) {
+	configDir, dataKey := newRecoveryTestVault(t, []byte("reinstalled machine"))
+
+	if _, err := loadDataKey(configDir, machineKeySource{}); err == nil {
+		t.Fatal("Expected the vault key not to unlock before recovery")
+	}
+
+	wrong := FormatRecoveryKey(bytes.Repeat([]byte{1}, keySize))
+	if err := RecoverWithKey(wrong); err == nil {
+		t.Error("Expected a recovery key that does not open the vault to fail")
+	}
+
+	if err := RecoverWithKey(FormatRecoveryKey(dataKey)); err != nil {
+		t.Fatalf("RecoverWithKey failed: %v", err)
+	}
+	assertVaultUnlocks(t, configDir)
+}
+
+func TestRecoverWithHostname(t *testing.T) {
+	configDir, _ := newRecoveryTestVault(t, hostnameIdentity("old-laptop"))
+
+	if err := RecoverWithHostname("other-laptop"); err == nil {
+		t.Error("Expected recovery with the wrong hostname to fail")
+	}
+
+	if err := RecoverWithHostname("old-laptop"); err != nil {
+		t.Fatalf("RecoverWithHostname failed: %v", err)
+	}
+	assertVaultUnlocks(t, configDir)
+}
+
+func TestRecoverWithHostnameReadsLegacyFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "")
+	configDir, err := config.Dir()
+	if err != nil {
+		t.Fatalf("config.Dir failed: %v", err)
+	}
+
+	// An mf 2.0 account file encrypted while the machine had another name.
+	data := []byte(`{"name":"AWS","secret":"JBSWY3DPEHPK3PXP"}`)
+	writeTestFile(t, filepath.Join(configDir, "AWS.enc"), sealLegacy(t, legacyMachineKey("old-laptop"), data))
+
+	if err := RecoverWithHostname("old-laptop"); err != nil {
+		t.Fatalf("RecoverWithHostname failed: %v", err)
+	}
+
+	dataKey, err := loadDataKey(configDir, machineKeySource{})
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	storage := &EncryptedStorage{configDir: configDir, key: dataKey}
+	if _, err := storage.Retrieve("AWS"); err != nil {
+		t.Errorf("Expected legacy account to be migrated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "AWS.enc")); !os.IsNotExist(err) {
+		t.Error("Legacy file should be removed after recovery")
+	}
+}
@@ -0,0 +1,326 @@
+package secure
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/zalando/go-keyring"
+	"mf/internal/config"
+	"mf/internal/fsutil"
+)
+
+// The vault is encrypted with a random data key. The data key is stored in
+// vault.key, wrapped with a key-encryption key (KEK) obtained from a key
+// source, so changing how the KEK is obtained never requires re-encrypting
+// the vault.
+const (
+	vaultKeyFile = "vault.key"
+
+	// KeySourceMachine derives the KEK from the machine identity.
+	KeySourceMachine = "machine"
+	// KeySourceKeyring keeps a random KEK in the OS keyring.
+	KeySourceKeyring = "keyring"
+
+	// The KEK lives under its own keyring service, so no account name can
+	// collide with it.
+	keyringKEKService = "mf-totp-vault"
+	keyringKEKUser    = "vault-kek"
+)
+
+// keySource provides the secret the KEK is derived from, and the KDF used to
+// derive it when the data key is wrapped.
+type keySource interface {
+	material(create bool) ([]byte, error)
+	newKDF() (kdfParams, error)
+}
+
+// migratingKeySource is a key source whose material changed between
+// versions. Vault keys that only open with the previous material are
+// re-wrapped on first use.
+type migratingKeySource interface {
+	keySource
+	previousMaterial() ([]byte, error)
+}
+
+// adoptingKeySource is a key source the user can switch to from the machine
+// identity. The switch is only made by AdoptKeySource, so a stray key file,
+// MF_KEY_FILE or config change cannot re-wrap the vault on its own.
+type adoptingKeySource interface {
+	keySource
+	adoptedMaterial() ([]byte, error)
+}
+
+// machineKeySource derives the KEK from the machine identity with PBKDF2 and
+// a random salt.
+type machineKeySource struct{}
+
+func (machineKeySource) material(bool) ([]byte, error) {
+	return MachineIdentity()
+}
+
+func (machineKeySource) newKDF() (kdfParams, error) {
+	return newPBKDF2Params()
+}
+
+// previousMaterial returns the hostname identity that wrapped vault keys
+// before machine IDs were read.
+func (machineKeySource) previousMaterial() ([]byte, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return hostnameIdentity(hostname), nil
+}
+
+// keyringKeySource keeps a random KEK in the OS keyring. It is created when
+// the data key is first wrapped.
+type keyringKeySource struct{}
+
+func (keyringKeySource) material(create bool) ([]byte, error) {
+	encoded, err := keyring.Get(keyringKEKService, keyringKEKUser)
+	if err == nil {
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+	if !create {
+		return nil, fmt.Errorf("vault key-encryption key not found in keyring: %w", err)
+	}
+
+	kek, err := randomKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := keyring.Set(keyringKEKService, keyringKEKUser, base64.StdEncoding.EncodeToString(kek)); err != nil {
+		return nil, fmt.Errorf("failed to store key-encryption key in keyring: %w", err)
+	}
+	return kek, nil
+}
+
+func (keyringKeySource) newKDF() (kdfParams, error) {
+	return kdfParams{ID: kdfNone}, nil
+}
+
+// adoptedMaterial lets a vault wrapped with the machine identity switch to
+// the keyring.
+func (keyringKeySource) adoptedMaterial() ([]byte, error) {
+	return MachineIdentity()
+}
+
+// newKeySource returns the key source in use: a key file from --key-file or
+// MF_KEY_FILE, then key_command and key_source in config.json.
+func newKeySource(cfg *config.Config) (keySource, error) {
+	if path := keyFilePath(); path != "" {
+		return fileKeySource{path: path}, nil
+	}
+	if cfg.KeyCommand != "" {
+		return commandKeySource{command: cfg.KeyCommand}, nil
+	}
+
+	switch cfg.KeySource {
+	case "", KeySourceMachine:
+		return machineKeySource{}, nil
+	case KeySourceKeyring:
+		return keyringKeySource{}, nil
+	default:
+		return nil, fmt.Errorf("unknown key source %q", cfg.KeySource)
+	}
+}
+
+// loadDataKey returns the vault data key, generating and wrapping a new one
+// on first use, when there is no vault yet. Unwrapping may run a slow KDF, a
+// key command or a passphrase prompt, so it happens without the lock; the
+// lock is only taken to create or re-wrap vault.key.
+func loadDataKey(configDir string, source keySource) ([]byte, error) {
+	data, err := readDataKey(configDir)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return lockedLoadDataKey(configDir, source, nil, nil, nil)
+	}
+
+	dataKey, err := unwrapDataKey(data, source)
+	if err == nil || isPassphraseProtected(data) {
+		return dataKey, err
+	}
+	if migrating, ok := source.(migratingKeySource); ok {
+		return lockedLoadDataKey(configDir, source, data, migrating.previousMaterial, err)
+	}
+	if adopting, ok := source.(adoptingKeySource); ok {
+		if previous, perr := adopting.adoptedMaterial(); perr == nil {
+			if _, perr := unwrapDataKeyWith(data, previous); perr == nil {
+				return nil, fmt.Errorf("vault key is still protected by the machine identity: run mf recover --adopt to protect it with the configured key")
+			}
+		}
+	}
+	return nil, err
+}
+
+// lockedLoadDataKey creates vault.key, or re-wraps it from previous material
+// under the lock. data is what the caller read without the lock; if another
+// process has changed vault.key since, it is unwrapped again instead.
+func lockedLoadDataKey(configDir string, source keySource, data []byte, previousMaterial func() ([]byte, error), unwrapErr error) ([]byte, error) {
+	var dataKey []byte
+	err := withLock(configDir, func() error {
+		current, err := readDataKey(configDir)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case current == nil:
+			// A new key could not open an existing vault, and its recovery
+			// key would be useless.
+			if _, err := os.Stat(filepath.Join(configDir, vaultFile)); err == nil {
+				return fmt.Errorf("%s is missing but %s exists: restore it or run mf recover --recovery-key", vaultKeyFile, vaultFile)
+			}
+			if dataKey, err = randomKey(); err != nil {
+				return err
+			}
+			if err := saveDataKey(configDir, source, dataKey); err != nil {
+				return err
+			}
+			if RecoveryKeyIssued != nil {
+				_, machine := source.(machineKeySource)
+				RecoveryKeyIssued(FormatRecoveryKey(dataKey), machine && usesHostnameIdentity())
+			}
+			return nil
+		case !bytes.Equal(current, data):
+			dataKey, err = unwrapDataKey(current, source)
+			return err
+		}
+
+		previous, err := previousMaterial()
+		if err != nil {
+			return unwrapErr
+		}
+		if dataKey, err = unwrapDataKeyWith(current, previous); err != nil {
+			return unwrapErr
+		}
+		return saveDataKey(configDir, source, dataKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dataKey, nil
+}
+
+// readDataKey returns the content of vault.key, or nil if it does not exist.
+func readDataKey(configDir string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, vaultKeyFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault key: %w", err)
+	}
+	return data, nil
+}
+
+func isPassphraseProtected(data []byte) bool {
+	env, err := parseEnvelope(data)
+	return err == nil && env.KDF.ID == kdfArgon2id
+}
+
+func unwrapDataKey(data []byte, source keySource) ([]byte, error) {
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault key file: %w", err)
+	}
+
+	// A passphrase-protected key overrides the configured source.
+	protected := env.KDF.ID == kdfArgon2id
+	if protected {
+		source = passphraseKeySource{}
+	}
+
+	material, err := source.material(false)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := openDataKey(env, material)
+	if err != nil {
+		if protected {
+			return nil, fmt.Errorf("failed to unwrap vault key: incorrect passphrase")
+		}
+		return nil, fmt.Errorf("failed to unwrap vault key: key-encryption key does not match (check the key file or key command, or use mf recover)")
+	}
+	return dataKey, nil
+}
+
+// unwrapDataKeyWith unwraps vault.key with explicit key material, whatever
+// source wrapped it.
+func unwrapDataKeyWith(data, material []byte) ([]byte, error) {
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault key file: %w", err)
+	}
+	return openDataKey(env, material)
+}
+
+func openDataKey(env *envelope, material []byte) ([]byte, error) {
+	kek, err := env.KDF.deriveKey(material)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := env.open(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) != keySize {
+		return nil, fmt.Errorf("invalid vault key size")
+	}
+	return dataKey, nil
+}
+
+// saveDataKey wraps the data key with a KEK from source and writes vault.key.
+func saveDataKey(configDir string, source keySource, dataKey []byte) error {
+	wrapped, err := wrapDataKey(source, dataKey)
+	if err != nil {
+		return err
+	}
+	return writeDataKey(configDir, wrapped)
+}
+
+// wrapDataKey seals the data key with a KEK from source.
+func wrapDataKey(source keySource, dataKey []byte) ([]byte, error) {
+	kdf, err := source.newKDF()
+	if err != nil {
+		return nil, err
+	}
+	material, err := source.material(true)
+	if err != nil {
+		return nil, err
+	}
+	kek, err := kdf.deriveKey(material)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, err := sealEnvelope(kek, kdf, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap vault key: %w", err)
+	}
+	return wrapped, nil
+}
+
+func writeDataKey(configDir string, wrapped []byte) error {
+	if err := fsutil.WriteFileAtomic(filepath.Join(configDir, vaultKeyFile), wrapped, 0600); err != nil {
+		return fmt.Errorf("failed to write vault key: %w", err)
+	}
+	return nil
+}
+
+func randomKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,190 @@
+package secure
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	"mf/internal/config"
+	"mf/internal/fsutil"
+	"mf/internal/types"
+)
+
+// staticKeySource is a key source with fixed material for tests.
+type staticKeySource []byte
+
+func (s staticKeySource) material(bool) ([]byte, error) {
+	return s, nil
+}
+
+func (staticKeySource) newKDF() (kdfParams, error) {
+	return newPBKDF2Params()
+}
+
+func TestLoadDataKey(t *testing.T) {
+	dir := t.TempDir()
+	source := staticKeySource("machine identity")
+
+	key, err := loadDataKey(dir, source)
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	if len(key) != keySize {
+		t.Fatalf("Expected %d byte data key, got %d", keySize, len(key))
+	}
+
+	wrapped, err := os.ReadFile(filepath.Join(dir, vaultKeyFile))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if bytes.Contains(wrapped, key) {
+		t.Error("Vault key file should not contain the data key in clear")
+	}
+
+	reloaded, err := loadDataKey(dir, source)
+	if err != nil {
+		t.Fatalf("Second loadDataKey failed: %v", err)
+	}
+	if !bytes.Equal(key, reloaded) {
+		t.Error("Data key should be stable across loads")
+	}
+
+	if _, err := loadDataKey(dir, staticKeySource("another machine")); err == nil {
+		t.Error("Expected a different machine identity to fail to unwrap the data key")
+	}
+}
+
+func TestLoadDataKeyIsRandomPerInstall(t *testing.T) {
+	source := staticKeySource("machine identity")
+
+	first, err := loadDataKey(t.TempDir(), source)
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	second, err := loadDataKey(t.TempDir(), source)
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+
+	if bytes.Equal(first, second) {
+		t.Error("Each installation should get its own random data key")
+	}
+}
+
+func TestLoadDataKeyRefusesToReplaceMissingKey(t *testing.T) {
+	dir := t.TempDir()
+	source := staticKeySource("machine identity")
+
+	if _, err := loadDataKey(dir, source); err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+	writeTestFile(t, filepath.Join(dir, vaultFile), []byte("vault"))
+	if err := os.Remove(filepath.Join(dir, vaultKeyFile)); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	issued := false
+	RecoveryKeyIssued = func(string, bool) { issued = true }
+	t.Cleanup(func() { RecoveryKeyIssued = nil })
+
+	if _, err := loadDataKey(dir, source); err == nil {
+		t.Fatal("Expected loadDataKey to fail when vault.key is missing and vault.enc exists")
+	}
+	if issued {
+		t.Error("No recovery key should be issued for an existing vault")
+	}
+	if _, err := os.Stat(filepath.Join(dir, vaultKeyFile)); !os.IsNotExist(err) {
+		t.Error("vault.key should not be written for an existing vault")
+	}
+}
+
+func TestKeyringKEKDoesNotCollideWithAccounts(t *testing.T) {
+	keyring.MockInit()
+
+	kek, err := keyringKeySource{}.material(true)
+	if err != nil {
+		t.Fatalf("material failed: %v", err)
+	}
+
+	// An account named like the KEK entry is stored by the keychain backend.
+	storage := &KeychainStorage{}
+	if err := storage.Store(types.Account{Name: keyringKEKUser, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	again, err := keyringKeySource{}.material(false)
+	if err != nil {
+		t.Fatalf("KEK unreadable after storing account %q: %v", keyringKEKUser, err)
+	}
+	if !bytes.Equal(kek, again) {
+		t.Error("Storing an account must not overwrite the vault KEK")
+	}
+}
+
+func TestKeyringKeySourceRequiresAdoption(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(KeyFileEnv, "")
+	t.Setenv(PassphraseEnv, "")
+
+	configDir, err := config.Dir()
+	if err != nil {
+		t.Fatalf("config.Dir failed: %v", err)
+	}
+	original, err := loadDataKey(configDir, machineKeySource{})
+	if err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+
+	if _, err := loadDataKey(configDir, keyringKeySource{}); err == nil || !strings.Contains(err.Error(), "mf recover --adopt") {
+		t.Fatalf("Expected loadDataKey to point to mf recover --adopt, got %v", err)
+	}
+
+	if err := (&config.Config{KeySource: KeySourceKeyring}).Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := AdoptKeySource(); err != nil {
+		t.Fatalf("AdoptKeySource failed: %v", err)
+	}
+
+	adopted, err := loadDataKey(configDir, keyringKeySource{})
+	if err != nil {
+		t.Fatalf("loadDataKey after adoption failed: %v", err)
+	}
+	if !bytes.Equal(adopted, original) {
+		t.Fatal("Expected the existing data key to be kept")
+	}
+}
+func TestLoadDataKeyDoesNotWaitForLock(t *testing.T) {
+	dir := t.TempDir()
+	source := staticKeySource("machine identity")
+	if _, err := loadDataKey(dir, source); err != nil {
+		t.Fatalf("loadDataKey failed: %v", err)
+	}
+
+	// Another process holds the lock, for example while it re-wraps the key.
+	lock, err := fsutil.LockDir(dir)
+	if err != nil {
+		t.Fatalf("LockDir failed: %v", err)
+	}
+	defer lock.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := loadDataKey(dir, source)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("loadDataKey failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Unwrapping an existing vault key should not wait for the lock")
+	}
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Account names are user input and may contain path separators, "..", or any
+// Unicode text, so they are encoded before being used as file names. Letters,
+// digits, "-", "_" and inner "." are kept; every other byte of the UTF-8 name
+// is written as %XX. The encoding is reversible, and ordinary names map to
+// themselves, so files written before it was introduced keep their names.
+
+// windowsReservedNames cannot be used as file names on Windows, with or
+// without an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+const hexDigits = "0123456789ABCDEF"
+
+// maxEncodedNameLength keeps the account file name within the 255-byte limit
+// most file systems have, counting the ".json" suffix and the "." prefix and
+// ".tmp-NNNNNNNNNN" suffix of the temporary file used to write it. Each
+// non-ASCII byte takes three, so a name of 27 CJK characters is too long.
+const maxEncodedNameLength = 255 - len(".json") - len("..tmp-") - 10
+
+// encodeName returns the file name stem for an account name.
+func encodeName(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("account name must not be empty")
+	}
+
+	stem, _, _ := strings.Cut(name, ".")
+	reserved := windowsReservedNames[strings.ToUpper(stem)]
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		// A leading "." would hide the file or form "..", and Windows drops
+		// a trailing one.
+		keep := isSafeNameByte(c) && !(i == 0 && (c == '.' || reserved)) &&
+			!(i == len(name)-1 && c == '.')
+		if keep {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0x0F])
+	}
+
+	if b.Len() > maxEncodedNameLength {
+		return "", fmt.Errorf("account name is too long: %d bytes when encoded, at most %d", b.Len(), maxEncodedNameLength)
+	}
+	return b.String(), nil
+}
+
+// decodeName reverses encodeName.
+func decodeName(encoded string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(encoded); i++ {
+		c := encoded[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+2 >= len(encoded) {
+			return "", fmt.Errorf("invalid escape in file name %q", encoded)
+		}
+		hi, lo := unhex(encoded[i+1]), unhex(encoded[i+2])
+		if hi < 0 || lo < 0 {
+			return "", fmt.Errorf("invalid escape in file name %q", encoded)
+		}
+		b.WriteByte(byte(hi<<4 | lo))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// isLegacyFileName reports whether name could have been used as a file name
+// directly by earlier versions without leaving the configuration directory.
+func isLegacyFileName(name string) bool {
+	return name != "" && name != "." && name != ".." &&
+		filepath.Base(name) == name && !strings.ContainsAny(name, `/\`)
+}
+
+func isSafeNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_' || c == '.'
+}
+
+func unhex(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'A' && c <= 'F':
+		return int(c - 'A' + 10)
+	case c >= 'a' && c <= 'f':
+		return int(c - 'a' + 10)
+	}
+	return -1
+}
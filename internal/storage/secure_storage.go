@@ -23,6 +23,12 @@ func (s *SecureStorage) SaveAccount(account types.Account) error {
 	return s.manager.Store(account)
 }
 
+// SaveAccounts stores a batch of accounts. The encrypted vault is written
+// once, so a failure leaves none of them saved.
+func (s *SecureStorage) SaveAccounts(accounts []types.Account) error {
+	return s.manager.StoreAll(accounts)
+}
+
 func (s *SecureStorage) LoadAccount(name string) (*types.Account, error) {
 	return s.manager.Retrieve(name)
 }
@@ -34,3 +40,7 @@ func (s *SecureStorage) ListAccounts() ([]string, error) {
 func (s *SecureStorage) DeleteAccount(name string) error {
 	return s.manager.Delete(name)
 }
+
+func (s *SecureStorage) UpdateAccount(name string, fn func(account *types.Account) error) (*types.Account, error) {
+	return s.manager.Update(name, fn)
+}
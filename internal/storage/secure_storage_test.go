@@ -7,6 +7,8 @@ import (
 )
 
 func TestSecureStorage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
 	store, err := NewSecure()
 	if err != nil {
 		t.Fatalf("NewSecure failed: %v", err)
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"mf/internal/config"
+	"mf/internal/fsutil"
 	"mf/internal/types"
 )
 
@@ -14,35 +16,43 @@ type Storage struct {
 }
 
 func New() (*Storage, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := config.Dir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user home directory: %w", err)
-	}
-
-	configDir := filepath.Join(homeDir, ".config", "mf")
-	if err := os.MkdirAll(configDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+		return nil, err
 	}
 
 	return &Storage{configDir: configDir}, nil
 }
 
 func (s *Storage) SaveAccount(account types.Account) error {
-	filename := filepath.Join(s.configDir, account.Name+".json")
+	filename, err := s.accountPath(account.Name)
+	if err != nil {
+		return err
+	}
+
 	data, err := json.MarshalIndent(account, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal account data: %w", err)
 	}
 
-	if err := os.WriteFile(filename, data, 0600); err != nil {
+	if err := fsutil.WriteFileAtomic(filename, data, 0600); err != nil {
 		return fmt.Errorf("failed to write account file: %w", err)
 	}
 
+	// Drop the unencoded copy written by earlier versions, if any.
+	if legacy, ok := s.legacyAccountPath(account.Name); ok && legacy != filename {
+		os.Remove(legacy)
+	}
+
 	return nil
 }
 
 func (s *Storage) LoadAccount(name string) (*types.Account, error) {
-	filename := filepath.Join(s.configDir, name+".json")
+	filename, err := s.existingAccountPath(name)
+	if err != nil {
+		return nil, err
+	}
+
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -66,10 +76,19 @@ func (s *Storage) ListAccounts() ([]string, error) {
 	}
 
 	var accounts []string
+	seen := make(map[string]bool)
 	for _, entry := range entries {
 		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
-			name := entry.Name()[:len(entry.Name())-5]
-			accounts = append(accounts, name)
+			stem := entry.Name()[:len(entry.Name())-5]
+			name, err := decodeName(stem)
+			if err != nil {
+				// A file from an earlier version whose name has a bare "%".
+				name = stem
+			}
+			if !seen[name] {
+				seen[name] = true
+				accounts = append(accounts, name)
+			}
 		}
 	}
 
@@ -77,7 +96,11 @@ func (s *Storage) ListAccounts() ([]string, error) {
 }
 
 func (s *Storage) DeleteAccount(name string) error {
-	filename := filepath.Join(s.configDir, name+".json")
+	filename, err := s.existingAccountPath(name)
+	if err != nil {
+		return err
+	}
+
 	if err := os.Remove(filename); err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("account '%s' not found", name)
@@ -87,3 +110,39 @@ func (s *Storage) DeleteAccount(name string) error {
 
 	return nil
 }
+
+// accountPath returns the file holding an account, with the name encoded so
+// it always stays inside the configuration directory.
+func (s *Storage) accountPath(name string) (string, error) {
+	encoded, err := encodeName(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.configDir, encoded+".json"), nil
+}
+
+// legacyAccountPath returns the unencoded file name earlier versions used for
+// an account, if it lies inside the configuration directory.
+func (s *Storage) legacyAccountPath(name string) (string, bool) {
+	if !isLegacyFileName(name) {
+		return "", false
+	}
+	return filepath.Join(s.configDir, name+".json"), true
+}
+
+// existingAccountPath returns accountPath, or the legacy file if only that
+// one exists.
+func (s *Storage) existingAccountPath(name string) (string, error) {
+	filename, err := s.accountPath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		if legacy, ok := s.legacyAccountPath(name); ok {
+			if _, err := os.Stat(legacy); err == nil {
+				return legacy, nil
+			}
+		}
+	}
+	return filename, nil
+}
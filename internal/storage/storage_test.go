@@ -3,6 +3,9 @@ package storage
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"mf/internal/types"
@@ -121,6 +124,8 @@ func TestStorage_DeleteNonExistentAccount(t *testing.T) {
 }
 
 func TestNew(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
 	storage, err := New()
 	if err != nil {
 		t.Fatalf("New failed: %v", err)
@@ -136,3 +141,203 @@ func TestNew(t *testing.T) {
 		t.Errorf("Expected config dir %s, got %s", expectedDir, storage.configDir)
 	}
 }
+
+// adversarialNames are account names that must not escape the configuration
+// directory or collide once used as file names.
+var adversarialNames = []string{
+	"../../.bashrc",
+	"..",
+	".",
+	".hidden",
+	"trailing.",
+	"a/b",
+	`a\b`,
+	"/etc/passwd",
+	"C:evil",
+	"CON",
+	"nul.txt",
+	"100%",
+	"%41",
+	"GitHub:alice@example.com",
+	"conta bancária",
+	"日本語",
+	"emoji 🔐",
+	"nul\x00byte",
+	"line\nbreak",
+	"a.json",
+}
+
+func TestStorage_AdversarialNames(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "config")
+	if err := os.Mkdir(configDir, 0700); err != nil {
+		t.Fatalf("Mkdir failed: %v", err)
+	}
+	storage := &Storage{configDir: configDir}
+
+	for _, name := range adversarialNames {
+		if err := storage.SaveAccount(types.Account{Name: name, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+			t.Fatalf("SaveAccount(%q) failed: %v", name, err)
+		}
+	}
+
+	// Every file must be a direct child of the configuration directory.
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Dir(path) != configDir {
+			t.Errorf("File written outside the config directory: %s", path)
+		}
+		if info.IsDir() && path != root && path != configDir {
+			t.Errorf("Unexpected directory created: %s", path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(configDir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != len(adversarialNames) {
+		t.Errorf("Expected %d account files, got %d", len(adversarialNames), len(entries))
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			t.Errorf("Account file should not be hidden: %s", entry.Name())
+		}
+	}
+
+	listed, err := storage.ListAccounts()
+	if err != nil {
+		t.Fatalf("ListAccounts failed: %v", err)
+	}
+	sort.Strings(listed)
+	expected := append([]string(nil), adversarialNames...)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(listed, expected) {
+		t.Errorf("ListAccounts returned %q, want %q", listed, expected)
+	}
+
+	for _, name := range adversarialNames {
+		account, err := storage.LoadAccount(name)
+		if err != nil {
+			t.Errorf("LoadAccount(%q) failed: %v", name, err)
+			continue
+		}
+		if account.Name != name {
+			t.Errorf("LoadAccount(%q) returned account %q", name, account.Name)
+		}
+		if err := storage.DeleteAccount(name); err != nil {
+			t.Errorf("DeleteAccount(%q) failed: %v", name, err)
+		}
+	}
+
+	if entries, _ := os.ReadDir(configDir); len(entries) != 0 {
+		t.Errorf("Expected all account files to be deleted, %d left", len(entries))
+	}
+}
+
+func TestStorage_RejectsEmptyName(t *testing.T) {
+	storage := &Storage{configDir: t.TempDir()}
+
+	if err := storage.SaveAccount(types.Account{Name: "", Secret: "JBSWY3DPEHPK3PXP"}); err == nil {
+		t.Error("Expected an empty account name to be rejected")
+	}
+}
+
+func TestStorage_RejectsLongName(t *testing.T) {
+	storage := &Storage{configDir: t.TempDir()}
+
+	// 100 CJK characters encode to 900 bytes, well past the file name limit.
+	name := strings.Repeat("日", 100)
+	err := storage.SaveAccount(types.Account{Name: name, Secret: "JBSWY3DPEHPK3PXP"})
+	if err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Fatalf("Expected a long account name to be rejected, got %v", err)
+	}
+
+	// The longest name that fits is still accepted.
+	name = strings.Repeat("a", maxEncodedNameLength)
+	if err := storage.SaveAccount(types.Account{Name: name, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+		t.Fatalf("SaveAccount failed: %v", err)
+	}
+	if _, err := storage.LoadAccount(name); err != nil {
+		t.Fatalf("LoadAccount failed: %v", err)
+	}
+}
+
+func TestStorage_ReadsLegacyFileNames(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage := &Storage{configDir: tmpDir}
+
+	// Written by an earlier version under the raw account name.
+	legacy := filepath.Join(tmpDir, "My Bank.json")
+	if err := os.WriteFile(legacy, []byte(`{"name":"My Bank","secret":"JBSWY3DPEHPK3PXP"}`), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	names, err := storage.ListAccounts()
+	if err != nil {
+		t.Fatalf("ListAccounts failed: %v", err)
+	}
+	if len(names) != 1 || names[0] != "My Bank" {
+		t.Errorf("Expected legacy account to be listed, got %q", names)
+	}
+
+	account, err := storage.LoadAccount("My Bank")
+	if err != nil {
+		t.Fatalf("LoadAccount failed: %v", err)
+	}
+
+	// Saving moves the account to its encoded file name.
+	if err := storage.SaveAccount(*account); err != nil {
+		t.Fatalf("SaveAccount failed: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Error("Legacy file should be replaced by the encoded one")
+	}
+	if names, _ := storage.ListAccounts(); len(names) != 1 {
+		t.Errorf("Expected one account after re-saving, got %q", names)
+	}
+
+	if err := storage.DeleteAccount("My Bank"); err != nil {
+		t.Fatalf("DeleteAccount failed: %v", err)
+	}
+}
+
+func TestEncodeName(t *testing.T) {
+	tests := map[string]string{
+		"GITHUB":        "GITHUB",
+		"aws-dev_2":     "aws-dev_2",
+		"my.account":    "my.account",
+		"../../.bashrc": "%2E.%2F..%2F.bashrc",
+		"a/b":           "a%2Fb",
+		"CON":           "%43ON",
+		"trailing.":     "trailing%2E",
+		"ação":          "a%C3%A7%C3%A3o",
+	}
+
+	for name, want := range tests {
+		got, err := encodeName(name)
+		if err != nil {
+			t.Fatalf("encodeName(%q) failed: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("encodeName(%q) = %q, want %q", name, got, want)
+		}
+
+		decoded, err := decodeName(got)
+		if err != nil || decoded != name {
+			t.Errorf("decodeName(%q) = %q, %v, want %q", got, decoded, err, name)
+		}
+	}
+
+	for _, invalid := range []string{"%", "%4", "%GG"} {
+		if _, err := decodeName(invalid); err == nil {
+			t.Errorf("Expected decodeName(%q) to fail", invalid)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package timesync measures the offset between the local clock and a
+// reference clock, either an (S)NTP server or the Date header of an HTTP
+// server.
+package timesync
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultServer  = "pool.ntp.org"
+	DefaultTimeout = 5 * time.Second
+
+	// ntpEpochOffset is the number of seconds between the NTP epoch
+	// (1900-01-01) and the Unix epoch.
+	ntpEpochOffset = 2208988800
+	ntpPacketSize  = 48
+)
+
+// Result describes one measurement. Offset is how far the reference clock is
+// ahead of the local clock; adding it to time.Now() gives the reference time.
+type Result struct {
+	Offset    time.Duration
+	RoundTrip time.Duration
+	Source    string
+}
+
+// QuerySNTP measures the clock offset against an SNTP/NTP server (RFC 4330).
+// The server may include a port; 123 is used otherwise.
+func QuerySNTP(server string, timeout time.Duration) (*Result, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	conn, err := net.DialTimeout("udp", server, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to time server: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set deadline: %w", err)
+	}
+
+	request := make([]byte, ntpPacketSize)
+	request[0] = 0x23 // LI = 0, VN = 4, Mode = 3 (client)
+
+	// The transmit timestamp only has to be echoed back, so a random value
+	// avoids leaking the local clock and lets us match the reply.
+	if _, err := rand.Read(request[40:48]); err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	response := make([]byte, ntpPacketSize)
+	n, err := conn.Read(response)
+	received := time.Now()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if n < ntpPacketSize {
+		return nil, fmt.Errorf("short response from time server")
+	}
+
+	if mode := response[0] & 0x07; mode != 4 && mode != 5 {
+		return nil, fmt.Errorf("unexpected response mode %d", mode)
+	}
+	if stratum := response[1]; stratum == 0 {
+		return nil, fmt.Errorf("time server refused the request (kiss-o'-death %q)", response[12:16])
+	}
+	if string(response[24:32]) != string(request[40:48]) {
+		return nil, fmt.Errorf("response does not match request")
+	}
+
+	serverReceive := fromNTP(response[32:40])
+	serverTransmit := fromNTP(response[40:48])
+
+	// Standard NTP clock offset and round-trip delay.
+	offset := (serverReceive.Sub(sent) + serverTransmit.Sub(received)) / 2
+	roundTrip := received.Sub(sent) - serverTransmit.Sub(serverReceive)
+
+	return &Result{Offset: offset, RoundTrip: roundTrip, Source: "sntp://" + server}, nil
+}
+
+// QueryHTTP estimates the clock offset from the Date header of an HTTP
+// response. The header only has one second resolution, so the result is
+// accurate to about half a second plus network jitter.
+func QueryHTTP(url string, timeout time.Duration) (*Result, error) {
+	client := &http.Client{Timeout: timeout}
+
+	request, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	sent := time.Now()
+	response, err := client.Do(request)
+	received := time.Now()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", url, err)
+	}
+	response.Body.Close()
+
+	header := response.Header.Get("Date")
+	if header == "" {
+		return nil, fmt.Errorf("response from %s has no Date header", url)
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Date header %q: %w", header, err)
+	}
+
+	// The server time lies somewhere within the reported second.
+	serverTime := date.Add(500 * time.Millisecond)
+	roundTrip := received.Sub(sent)
+	localTime := sent.Add(roundTrip / 2)
+
+	return &Result{Offset: serverTime.Sub(localTime), RoundTrip: roundTrip, Source: url}, nil
+}
+
+func fromNTP(b []byte) time.Time {
+	seconds := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	fraction := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(seconds, fraction*1e9>>32)
+}
@@ -0,0 +1,118 @@
+package timesync
+
+import (
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// toNTP is the inverse of fromNTP, used by the test server.
+func toNTP(t time.Time, b []byte) {
+	seconds := uint32(t.Unix() + ntpEpochOffset)
+	fraction := uint32((int64(t.Nanosecond()) << 32) / 1e9)
+	binary.BigEndian.PutUint32(b[0:4], seconds)
+	binary.BigEndian.PutUint32(b[4:8], fraction)
+}
+
+// startSNTPServer runs a minimal SNTP server whose clock is skew ahead of
+// the local clock.
+func startSNTPServer(t *testing.T, skew time.Duration, stratum byte) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start SNTP server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, ntpPacketSize)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < ntpPacketSize {
+				continue
+			}
+
+			response := make([]byte, ntpPacketSize)
+			response[0] = 0x24 // LI = 0, VN = 4, Mode = 4 (server)
+			response[1] = stratum
+			copy(response[24:32], buf[40:48])
+			toNTP(time.Now().Add(skew), response[32:40])
+			toNTP(time.Now().Add(skew), response[40:48])
+			conn.WriteTo(response, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQuerySNTP(t *testing.T) {
+	for _, skew := range []time.Duration{0, 42 * time.Second, -90 * time.Second} {
+		server := startSNTPServer(t, skew, 2)
+
+		result, err := QuerySNTP(server, time.Second)
+		if err != nil {
+			t.Fatalf("QuerySNTP failed: %v", err)
+		}
+
+		if diff := result.Offset - skew; diff > 100*time.Millisecond || diff < -100*time.Millisecond {
+			t.Errorf("Expected offset close to %v, got %v", skew, result.Offset)
+		}
+		if result.RoundTrip < 0 {
+			t.Errorf("Round trip should not be negative, got %v", result.RoundTrip)
+		}
+	}
+}
+
+func TestQuerySNTPKissOfDeath(t *testing.T) {
+	server := startSNTPServer(t, 0, 0)
+
+	if _, err := QuerySNTP(server, time.Second); err == nil {
+		t.Error("Expected error for stratum 0 response")
+	}
+}
+
+func TestQuerySNTPTimeout(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := QuerySNTP(conn.LocalAddr().String(), 200*time.Millisecond); err == nil {
+		t.Error("Expected error when the server does not answer")
+	}
+}
+
+func TestQueryHTTP(t *testing.T) {
+	skew := -75 * time.Second
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	result, err := QueryHTTP(server.URL, time.Second)
+	if err != nil {
+		t.Fatalf("QueryHTTP failed: %v", err)
+	}
+
+	if diff := result.Offset - skew; diff > 1100*time.Millisecond || diff < -1100*time.Millisecond {
+		t.Errorf("Expected offset close to %v, got %v", skew, result.Offset)
+	}
+}
+
+func TestNTPTimestampRoundTrip(t *testing.T) {
+	original := time.Unix(1700000000, 123456789)
+	buf := make([]byte, 8)
+	toNTP(original, buf)
+
+	if diff := fromNTP(buf).Sub(original); diff > time.Microsecond || diff < -time.Microsecond {
+		t.Errorf("NTP timestamp round trip drifted by %v", diff)
+	}
+}
@@ -0,0 +1,38 @@
+package totp
+
+import "time"
+
+// Clock supplies the current time used for code generation.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock reads the machine clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// OffsetClock corrects another clock by a fixed offset, e.g. the measured
+// drift between the machine and a time server.
+type OffsetClock struct {
+	Base   Clock
+	Offset time.Duration
+}
+
+func (c OffsetClock) Now() time.Time {
+	base := c.Base
+	if base == nil {
+		base = SystemClock{}
+	}
+	return base.Now().Add(c.Offset)
+}
+
+// FixedClock always returns the same instant. It is useful for generating
+// codes for an arbitrary time and for tests.
+type FixedClock time.Time
+
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
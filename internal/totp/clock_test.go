@@ -0,0 +1,33 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOffsetClock(t *testing.T) {
+	base := FixedClock(time.Unix(1000, 0))
+
+	clock := OffsetClock{Base: base, Offset: -1500 * time.Millisecond}
+	if got := clock.Now(); !got.Equal(time.Unix(998, 500*int64(time.Millisecond))) {
+		t.Errorf("Expected offset clock to read 998.5, got %v", got)
+	}
+
+	if drift := (OffsetClock{Offset: time.Hour}).Now().Sub(time.Now()); drift < 59*time.Minute {
+		t.Errorf("Expected offset clock without base to use system time, drift was %v", drift)
+	}
+}
+
+func TestGenerateTokenWithClock(t *testing.T) {
+	// The machine clock reads t=29 but is 30 seconds behind, so the code must
+	// be the one for t=59.
+	clock := OffsetClock{Base: FixedClock(time.Unix(29, 0)), Offset: 30 * time.Second}
+
+	token, err := GenerateTokenWithClock(rfcSecretSHA1, Params{Digits: 8}, clock)
+	if err != nil {
+		t.Fatalf("GenerateTokenWithClock failed: %v", err)
+	}
+	if token != "94287082" {
+		t.Errorf("Expected 94287082, got %s", token)
+	}
+}
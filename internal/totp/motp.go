@@ -0,0 +1,56 @@
+package totp
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MOTPPeriod is the validity of a Mobile-OTP code in seconds.
+const MOTPPeriod = 10
+
+// GenerateMOTP generates a Mobile-OTP code: the first six hex characters of
+// MD5(epoch/10 || secret || PIN), with the epoch written in decimal and the
+// secret as the hex string shared with the server.
+func GenerateMOTP(secret, pin string, t time.Time) (string, error) {
+	secret, err := NormalizeMOTPSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate mOTP token: %w", err)
+	}
+	if err := ValidatePIN(pin); err != nil {
+		return "", fmt.Errorf("failed to generate mOTP token: %w", err)
+	}
+
+	step := strconv.FormatInt(t.Unix()/MOTPPeriod, 10)
+	sum := md5.Sum([]byte(step + secret + pin))
+	return hex.EncodeToString(sum[:])[:6], nil
+}
+
+// NormalizeMOTPSecret returns an mOTP init-secret as lowercase hex without
+// spaces. mOTP secrets are usually 16 or 32 hex characters.
+func NormalizeMOTPSecret(secret string) (string, error) {
+	secret = strings.ToLower(stripSpaces(secret))
+	if len(secret) < 8 {
+		return "", fmt.Errorf("invalid mOTP secret: too short")
+	}
+	if _, err := hex.DecodeString(secret); err != nil {
+		return "", fmt.Errorf("invalid mOTP secret: not valid hex")
+	}
+	return secret, nil
+}
+
+// ValidatePIN checks that a PIN is made of decimal digits only.
+func ValidatePIN(pin string) error {
+	if pin == "" {
+		return fmt.Errorf("PIN is required")
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("PIN must contain only digits")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateMOTP(t *testing.T) {
+	// Expected values computed with the reference mOTP definition,
+	// md5(epoch/10 || secret || pin)[:6].
+	tests := []struct {
+		secret   string
+		pin      string
+		unix     int64
+		expected string
+	}{
+		{"e3152afee62599c8", "1234", 0, "2c244b"},
+		{"e3152afee62599c8", "1234", 1700000000, "ac896a"},
+		{"e3152afee62599c8", "1234", 1700000009, "ac896a"},
+		{"E3152AFE E62599C8", "0000", 1700000010, "ebca6c"},
+		{"1234567890abcdef", "9876", 1111111109, "58df04"},
+	}
+
+	for _, tt := range tests {
+		token, err := GenerateMOTP(tt.secret, tt.pin, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateMOTP(%s, %d) failed: %v", tt.secret, tt.unix, err)
+		}
+		if token != tt.expected {
+			t.Errorf("GenerateMOTP(%s, %s, %d) = %s, expected %s", tt.secret, tt.pin, tt.unix, token, tt.expected)
+		}
+	}
+}
+
+func TestGenerateMOTPInvalid(t *testing.T) {
+	invalid := []struct {
+		secret string
+		pin    string
+	}{
+		{"e3152afee62599c8", ""},
+		{"e3152afee62599c8", "12a4"},
+		{"not-hex-secret!!", "1234"},
+		{"abc", "1234"},
+	}
+
+	for _, tt := range invalid {
+		if _, err := GenerateMOTP(tt.secret, tt.pin, time.Unix(0, 0)); err == nil {
+			t.Errorf("Expected GenerateMOTP(%q, %q) to fail", tt.secret, tt.pin)
+		}
+	}
+}
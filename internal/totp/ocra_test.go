@@ -0,0 +1,141 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// ocraTimestamp is the time step (in minutes) used by the RFC 6287 vectors.
+var ocraTimestamp = time.Unix(0x132d0b6*60, 0)
+
+func TestGenerateOCRARFC6287Vectors(t *testing.T) {
+	// RFC 6287 Appendix C. The keys are the same as RFC 6238.
+	tests := []struct {
+		secret   string
+		suite    string
+		input    OCRAInput
+		expected string
+	}{
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "00000000"}, "237653"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "11111111"}, "243178"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "22222222"}, "653583"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "33333333"}, "740991"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "44444444"}, "608993"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "55555555"}, "388898"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "66666666"}, "816933"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "77777777"}, "224598"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "88888888"}, "750600"},
+		{rfcSecretSHA1, "OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "99999999"}, "294470"},
+
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", OCRAInput{Counter: 0, Challenge: "12345678", Password: "1234"}, "65347737"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", OCRAInput{Counter: 1, Challenge: "12345678", Password: "1234"}, "86775851"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", OCRAInput{Counter: 2, Challenge: "12345678", Password: "1234"}, "78192410"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", OCRAInput{Counter: 9, Challenge: "12345678", Password: "1234"}, "08522129"},
+
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QN08-PSHA1", OCRAInput{Challenge: "00000000", Password: "1234"}, "83238735"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QN08-PSHA1", OCRAInput{Challenge: "11111111", Password: "1234"}, "01501458"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QN08-PSHA1", OCRAInput{Challenge: "44444444", Password: "1234"}, "86807031"},
+
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:C-QN08", OCRAInput{Counter: 0, Challenge: "00000000"}, "07016083"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:C-QN08", OCRAInput{Counter: 1, Challenge: "11111111"}, "63947962"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:C-QN08", OCRAInput{Counter: 2, Challenge: "22222222"}, "70123924"},
+
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:QN08-T1M", OCRAInput{Challenge: "00000000", Time: ocraTimestamp}, "95209754"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:QN08-T1M", OCRAInput{Challenge: "11111111", Time: ocraTimestamp}, "55907591"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:QN08-T1M", OCRAInput{Challenge: "44444444", Time: ocraTimestamp}, "36209546"},
+
+		// Mutual challenge-response: the client and server challenges are
+		// concatenated, so Q may be longer than the suite's length.
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QA08", OCRAInput{Challenge: "CLI22220SRV11110"}, "28247970"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QA08", OCRAInput{Challenge: "CLI22221SRV11111"}, "01984843"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QA08", OCRAInput{Challenge: "SRV11110CLI22220"}, "15510767"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:QA08", OCRAInput{Challenge: "CLI22220SRV11110"}, "79496648"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:QA08-PSHA1", OCRAInput{Challenge: "SRV11110CLI22220", Password: "1234"}, "18806276"},
+
+		// Plain signature.
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QA08", OCRAInput{Challenge: "SIG10000"}, "53095496"},
+		{rfcSecretSHA256, "OCRA-1:HOTP-SHA256-8:QA08", OCRAInput{Challenge: "SIG11000"}, "04110475"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:QA10-T1M", OCRAInput{Challenge: "SIG1000000", Time: ocraTimestamp}, "77537423"},
+		{rfcSecretSHA512, "OCRA-1:HOTP-SHA512-8:QA10-T1M", OCRAInput{Challenge: "SIG1100000", Time: ocraTimestamp}, "31970405"},
+	}
+
+	for _, tt := range tests {
+		response, err := GenerateOCRA(tt.secret, tt.suite, tt.input)
+		if err != nil {
+			t.Fatalf("GenerateOCRA(%s, %+v) failed: %v", tt.suite, tt.input, err)
+		}
+		if response != tt.expected {
+			t.Errorf("GenerateOCRA(%s, %+v) = %s, expected %s", tt.suite, tt.input, response, tt.expected)
+		}
+	}
+}
+
+func TestParseOCRASuite(t *testing.T) {
+	suite, err := ParseOCRASuite("OCRA-1:HOTP-SHA512-8:C-QH40-PSHA256-S128-T30S")
+	if err != nil {
+		t.Fatalf("ParseOCRASuite failed: %v", err)
+	}
+
+	expected := OCRASuite{
+		Suite:             "OCRA-1:HOTP-SHA512-8:C-QH40-PSHA256-S128-T30S",
+		Algorithm:         "SHA512",
+		Digits:            8,
+		Counter:           true,
+		ChallengeFormat:   'H',
+		ChallengeLength:   40,
+		PasswordAlgorithm: "SHA256",
+		SessionLength:     128,
+		TimeStep:          30 * time.Second,
+	}
+	if suite != expected {
+		t.Errorf("ParseOCRASuite = %+v, expected %+v", suite, expected)
+	}
+}
+
+func TestParseOCRASuiteInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"OCRA-1:HOTP-SHA1-6",
+		"OCRA-2:HOTP-SHA1-6:QN08",
+		"OCRA-1:TOTP-SHA1-6:QN08",
+		"OCRA-1:HOTP-MD5-6:QN08",
+		"OCRA-1:HOTP-SHA1-3:QN08",
+		"OCRA-1:HOTP-SHA1-11:QN08",
+		"OCRA-1:HOTP-SHA1-6:C",
+		"OCRA-1:HOTP-SHA1-6:QX08",
+		"OCRA-1:HOTP-SHA1-6:QN03",
+		"OCRA-1:HOTP-SHA1-6:QN65",
+		"OCRA-1:HOTP-SHA1-6:QN08-PMD5",
+		"OCRA-1:HOTP-SHA1-6:QN08-T60S",
+		"OCRA-1:HOTP-SHA1-6:QN08-X",
+	}
+
+	for _, suite := range invalid {
+		if _, err := ParseOCRASuite(suite); err == nil {
+			t.Errorf("Expected ParseOCRASuite(%q) to fail", suite)
+		}
+	}
+}
+
+func TestGenerateOCRAInvalidInput(t *testing.T) {
+	invalid := []struct {
+		suite string
+		input OCRAInput
+	}{
+		{"OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{}},
+		{"OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "123"}},
+		{"OCRA-1:HOTP-SHA1-6:QA64", OCRAInput{Challenge: strings.Repeat("A", 129)}},
+		{"OCRA-1:HOTP-SHA1-6:QN08", OCRAInput{Challenge: "12ab"}},
+		{"OCRA-1:HOTP-SHA1-6:QH08", OCRAInput{Challenge: "zzzzzz"}},
+		{"OCRA-1:HOTP-SHA1-6:QN08-PSHA1", OCRAInput{Challenge: "12345678"}},
+		{"OCRA-1:HOTP-SHA1-6:QN08-S004", OCRAInput{Challenge: "12345678", Session: "0102030405"}},
+		{"OCRA-1:HOTP-SHA1-6:QN08-S004", OCRAInput{Challenge: "12345678", Session: "not hex"}},
+	}
+
+	for _, tt := range invalid {
+		if _, err := GenerateOCRA(rfcSecretSHA1, tt.suite, tt.input); err == nil {
+			t.Errorf("Expected GenerateOCRA(%s, %+v) to fail", tt.suite, tt.input)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package totp
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	EncodingBase32 = "base32"
+	EncodingHex    = "hex"
+	EncodingBase64 = "base64"
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NormalizeSecret accepts a base32 secret the way providers tend to display
+// it (grouped with spaces, lowercase, with or without '=' padding) and
+// returns it in canonical form: uppercase and without padding.
+func NormalizeSecret(secret string) (string, error) {
+	normalized := strings.TrimRight(strings.ToUpper(stripSpaces(secret)), "=")
+	if normalized == "" {
+		return "", fmt.Errorf("invalid secret: empty")
+	}
+
+	key, err := base32NoPadding.DecodeString(normalized)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret: not valid base32")
+	}
+	if len(key) == 0 {
+		return "", fmt.Errorf("invalid secret: empty")
+	}
+
+	return normalized, nil
+}
+
+// ConvertSecret decodes a secret given in the specified encoding (base32,
+// hex or base64) and returns it as canonical base32.
+func ConvertSecret(secret, encoding string) (string, error) {
+	var key []byte
+	var err error
+
+	switch strings.ToLower(encoding) {
+	case "", EncodingBase32:
+		return NormalizeSecret(secret)
+	case EncodingHex:
+		cleaned := strings.NewReplacer(":", "", "-", "").Replace(stripSpaces(secret))
+		cleaned = strings.TrimPrefix(strings.TrimPrefix(cleaned, "0x"), "0X")
+		key, err = hex.DecodeString(cleaned)
+		if err != nil {
+			return "", fmt.Errorf("invalid secret: not valid hex")
+		}
+	case EncodingBase64:
+		key, err = decodeBase64(stripSpaces(secret))
+		if err != nil {
+			return "", fmt.Errorf("invalid secret: not valid base64")
+		}
+	default:
+		return "", fmt.Errorf("unsupported secret encoding: %s", encoding)
+	}
+
+	if len(key) == 0 {
+		return "", fmt.Errorf("invalid secret: empty")
+	}
+
+	return base32NoPadding.EncodeToString(key), nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if key, err := base64.RawStdEncoding.DecodeString(s); err == nil {
+		return key, nil
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func stripSpaces(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// decodeSecret decodes a base32 secret, tolerating lowercase and missing
+// padding.
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.TrimRight(strings.ToUpper(strings.TrimSpace(secret)), "=")
+
+	key, err := base32NoPadding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base32 secret: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,93 @@
+package totp
+
+import "testing"
+
+func TestNormalizeSecret(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"JBSWY3DPEHPK3PXP", "JBSWY3DPEHPK3PXP"},
+		{"jbsw y3dp ehpk 3pxp", "JBSWY3DPEHPK3PXP"},
+		{"  JBSWY3DP\tEHPK3PXP\n", "JBSWY3DPEHPK3PXP"},
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA====", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"},
+		{"gezdgnbvgy3tqojqgezdgnbvgy3tqojqgeza", "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"},
+	}
+
+	for _, tt := range tests {
+		normalized, err := NormalizeSecret(tt.input)
+		if err != nil {
+			t.Errorf("NormalizeSecret(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if normalized != tt.expected {
+			t.Errorf("NormalizeSecret(%q) = %s, expected %s", tt.input, normalized, tt.expected)
+		}
+		if err := ValidateSecret(normalized); err != nil {
+			t.Errorf("Normalized secret %s should be valid: %v", normalized, err)
+		}
+	}
+}
+
+func TestNormalizeSecretInvalid(t *testing.T) {
+	invalid := []string{"", "   ", "====", "invalid-secret", "JBSWY3DPEHPK3PX1", "A"}
+
+	for _, input := range invalid {
+		if _, err := NormalizeSecret(input); err == nil {
+			t.Errorf("Expected NormalizeSecret(%q) to fail", input)
+		}
+	}
+}
+
+func TestConvertSecret(t *testing.T) {
+	// All of these are the RFC 4226 key "12345678901234567890".
+	tests := []struct {
+		input    string
+		encoding string
+	}{
+		{"3132333435363738393031323334353637383930", "hex"},
+		{"31 32 33 34 35 36 37 38 39 30 31 32 33 34 35 36 37 38 39 30", "HEX"},
+		{"0x3132333435363738393031323334353637383930", "hex"},
+		{"31:32:33:34:35:36:37:38:39:30:31:32:33:34:35:36:37:38:39:30", "hex"},
+		{"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=", "base64"},
+		{"MTIzNDU2Nzg5MDEyMzQ1Njc4OTA", "base64"},
+		{"gezd gnbv gy3t qojq gezd gnbv gy3t qojq", "base32"},
+		{"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", ""},
+	}
+
+	for _, tt := range tests {
+		converted, err := ConvertSecret(tt.input, tt.encoding)
+		if err != nil {
+			t.Errorf("ConvertSecret(%q, %s) failed: %v", tt.input, tt.encoding, err)
+			continue
+		}
+		if converted != rfcSecretSHA1 {
+			t.Errorf("ConvertSecret(%q, %s) = %s, expected %s", tt.input, tt.encoding, converted, rfcSecretSHA1)
+		}
+	}
+
+	// Steam shared secrets are distributed as base64, including '/' and '+'.
+	converted, err := ConvertSecret("cnOgv/KdpLoP6Nbh0GMkXkPXALQ=", "base64")
+	if err != nil || converted != "OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU" {
+		t.Errorf("Expected Steam shared secret to convert, got %s (%v)", converted, err)
+	}
+}
+
+func TestConvertSecretInvalid(t *testing.T) {
+	invalid := []struct {
+		input    string
+		encoding string
+	}{
+		{"31323", "hex"},
+		{"zz", "hex"},
+		{"", "hex"},
+		{"!!!", "base64"},
+		{"JBSWY3DPEHPK3PXP", "base58"},
+	}
+
+	for _, tt := range invalid {
+		if _, err := ConvertSecret(tt.input, tt.encoding); err == nil {
+			t.Errorf("Expected ConvertSecret(%q, %s) to fail", tt.input, tt.encoding)
+		}
+	}
+}
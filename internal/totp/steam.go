@@ -0,0 +1,26 @@
+package totp
+
+import (
+	"time"
+)
+
+const (
+	steamPeriod   = 30
+	steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+	steamDigits   = 5
+)
+
+// SteamParams generates Steam Guard codes: a standard HMAC-SHA1 TOTP over
+// 30 second steps whose truncated value is written with Steam's
+// 26-character alphabet instead of decimal digits.
+var SteamParams = Params{
+	Algorithm: "SHA1",
+	Digits:    steamDigits,
+	Period:    steamPeriod,
+	Alphabet:  steamAlphabet,
+}
+
+// GenerateSteamToken generates the Steam Guard code valid at t.
+func GenerateSteamToken(secret string, t time.Time) (string, error) {
+	return GenerateTokenAt(secret, SteamParams, t)
+}
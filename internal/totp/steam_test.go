@@ -0,0 +1,41 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateSteamToken(t *testing.T) {
+	// Expected values computed with the reference algorithm used by the
+	// Steam mobile authenticator and community clients (steampy, SDA).
+	tests := []struct {
+		secret   string
+		unix     int64
+		expected string
+	}{
+		{rfcSecretSHA1, 59, "PV9M4"},
+		{rfcSecretSHA1, 1111111109, "PY4YB"},
+		{rfcSecretSHA1, 1234567890, "VHHQY"},
+		{rfcSecretSHA1, 2000000000, "9N776"},
+		// shared_secret "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=" in base32
+		{"OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU", 0, "W3J46"},
+		{"OJZ2BP7STWSLUD7I23Q5AYZELZB5OAFU", 1600000000, "H6G3P"},
+		{"ojz2bp7stwslud7i23q5ayzelzb5oafu", 1700000000, "X45RP"},
+	}
+
+	for _, tt := range tests {
+		token, err := GenerateSteamToken(tt.secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateSteamToken(%d) failed: %v", tt.unix, err)
+		}
+		if token != tt.expected {
+			t.Errorf("GenerateSteamToken(%s, %d) = %s, expected %s", tt.secret, tt.unix, token, tt.expected)
+		}
+	}
+}
+
+func TestGenerateSteamTokenInvalidSecret(t *testing.T) {
+	if _, err := GenerateSteamToken("invalid-secret", time.Now()); err == nil {
+		t.Error("Expected error when generating Steam token with invalid secret")
+	}
+}
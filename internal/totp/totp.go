@@ -1,24 +1,215 @@
+// Package totp implements HOTP (RFC 4226) and TOTP (RFC 6238) code
+// generation, plus the non-standard variants built on top of them.
+//
+// Generation is driven by a Params value and, for time-based codes, a Clock,
+// so codes can be produced for any instant and tested deterministically.
 package totp
 
 import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
 	"fmt"
+	"hash"
+	"strings"
 	"time"
+)
 
-	"github.com/pquerna/otp/totp"
+const (
+	DefaultAlgorithm = "SHA1"
+	DefaultDigits    = 6
+	DefaultPeriod    = 30
 )
 
+// Params holds the parameters of a code. Zero values fall back to the
+// RFC 6238 defaults (SHA1, 6 digits, 30 seconds, T0 = 0).
+type Params struct {
+	Algorithm string
+	Digits    int
+	Period    int
+	T0        int64
+	// Alphabet, when set, replaces decimal digits: the truncated value is
+	// written least significant symbol first using these characters, as
+	// Steam Guard does. Digits is then the number of symbols.
+	Alphabet string
+}
+
+// HOTP computes the RFC 4226 code for a raw key and counter.
+func HOTP(key []byte, counter uint64, params Params) (string, error) {
+	newHash, err := ParseAlgorithm(params.Algorithm)
+	if err != nil {
+		return "", err
+	}
+	digits, err := params.digits()
+	if err != nil {
+		return "", err
+	}
+
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(newHash, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3).
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	if params.Alphabet != "" {
+		return formatAlphabet(value, digits, params.Alphabet), nil
+	}
+	return formatDecimal(value, digits), nil
+}
+
+// TOTP computes the RFC 6238 code for a raw key at the time read from clock.
+func TOTP(key []byte, clock Clock, params Params) (string, error) {
+	counter, err := params.Counter(clock.Now())
+	if err != nil {
+		return "", err
+	}
+	return HOTP(key, counter, params)
+}
+
+// Counter returns the time step number T = floor((t - T0) / period).
+func (p Params) Counter(t time.Time) (uint64, error) {
+	period, err := p.period()
+	if err != nil {
+		return 0, err
+	}
+
+	elapsed := t.Unix() - p.T0
+	if elapsed < 0 {
+		return 0, fmt.Errorf("time is before T0")
+	}
+	return uint64(elapsed) / uint64(period), nil
+}
+
+// Validate checks that the parameters describe a supported configuration.
+func (p Params) Validate() error {
+	if _, err := ParseAlgorithm(p.Algorithm); err != nil {
+		return err
+	}
+	if _, err := p.digits(); err != nil {
+		return err
+	}
+	if _, err := p.period(); err != nil {
+		return err
+	}
+	if p.T0 < 0 {
+		return fmt.Errorf("invalid T0: %d", p.T0)
+	}
+	return nil
+}
+
+func (p Params) digits() (int, error) {
+	digits := p.Digits
+	if digits == 0 {
+		digits = DefaultDigits
+	}
+
+	if p.Alphabet != "" {
+		if len(p.Alphabet) < 2 || digits < 1 || digits > 10 {
+			return 0, fmt.Errorf("unsupported code length: %d", digits)
+		}
+		return digits, nil
+	}
+
+	if digits < 6 || digits > 8 {
+		return 0, fmt.Errorf("unsupported number of digits: %d", digits)
+	}
+	return digits, nil
+}
+
+func (p Params) period() (int, error) {
+	switch {
+	case p.Period == 0:
+		return DefaultPeriod, nil
+	case p.Period < 0:
+		return 0, fmt.Errorf("invalid period: %d", p.Period)
+	default:
+		return p.Period, nil
+	}
+}
+
+// ParseAlgorithm maps an algorithm name (case-insensitive, empty means SHA1)
+// to the hash function used in the HMAC.
+func ParseAlgorithm(name string) (func() hash.Hash, error) {
+	switch strings.ToUpper(name) {
+	case "", "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported algorithm: %s", name)
+	}
+}
+
+func formatDecimal(value uint32, digits int) string {
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}
+
+func formatAlphabet(value uint32, length int, alphabet string) string {
+	code := make([]byte, length)
+	base := uint32(len(alphabet))
+	for i := range code {
+		code[i] = alphabet[value%base]
+		value /= base
+	}
+	return string(code)
+}
+
 func GenerateToken(secret string) (string, error) {
-	token, err := totp.GenerateCode(secret, time.Now())
+	return GenerateTokenWithClock(secret, Params{}, SystemClock{})
+}
+
+func GenerateTokenWithClock(secret string, params Params, clock Clock) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate TOTP token: %w", err)
+	}
+
+	token, err := TOTP(key, clock, params)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate TOTP token: %w", err)
 	}
 	return token, nil
 }
 
+func GenerateTokenAt(secret string, params Params, t time.Time) (string, error) {
+	return GenerateTokenWithClock(secret, params, FixedClock(t))
+}
+
+// GenerateHOTP generates an RFC 4226 counter-based token. Period and T0 are
+// ignored for HOTP.
+func GenerateHOTP(secret string, counter uint64, params Params) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate HOTP token: %w", err)
+	}
+
+	token, err := HOTP(key, counter, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate HOTP token: %w", err)
+	}
+	return token, nil
+}
+
 func ValidateSecret(secret string) error {
-	_, err := totp.GenerateCode(secret, time.Now())
+	key, err := decodeSecret(secret)
 	if err != nil {
 		return fmt.Errorf("invalid secret: %w", err)
 	}
+	if len(key) == 0 {
+		return fmt.Errorf("invalid secret: empty")
+	}
 	return nil
 }
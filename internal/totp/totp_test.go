@@ -2,6 +2,7 @@ package totp
 
 import (
 	"testing"
+	"time"
 )
 
 func TestGenerateToken(t *testing.T) {
@@ -66,3 +67,213 @@ func TestGenerateTokenConsistency(t *testing.T) {
 		t.Errorf("Tokens should be the same within the same time window, got %s and %s", token1, token2)
 	}
 }
+
+const (
+	rfcSecretSHA1   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	rfcSecretSHA256 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA===="
+	rfcSecretSHA512 = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA="
+)
+
+func TestGenerateTokenAtRFC6238Vectors(t *testing.T) {
+	// RFC 6238 Appendix B.
+	secrets := map[string]string{
+		"SHA1":   rfcSecretSHA1,
+		"SHA256": rfcSecretSHA256,
+		"SHA512": rfcSecretSHA512,
+	}
+
+	tests := []struct {
+		unix     int64
+		expected map[string]string
+	}{
+		{59, map[string]string{"SHA1": "94287082", "SHA256": "46119246", "SHA512": "90693936"}},
+		{1111111109, map[string]string{"SHA1": "07081804", "SHA256": "68084774", "SHA512": "25091201"}},
+		{1111111111, map[string]string{"SHA1": "14050471", "SHA256": "67062674", "SHA512": "99943326"}},
+		{1234567890, map[string]string{"SHA1": "89005924", "SHA256": "91819424", "SHA512": "93441116"}},
+		{2000000000, map[string]string{"SHA1": "69279037", "SHA256": "90698825", "SHA512": "38618901"}},
+		{20000000000, map[string]string{"SHA1": "65353130", "SHA256": "77737706", "SHA512": "47863826"}},
+	}
+
+	for _, tt := range tests {
+		for algorithm, expected := range tt.expected {
+			params := Params{Algorithm: algorithm, Digits: 8}
+			token, err := GenerateTokenAt(secrets[algorithm], params, time.Unix(tt.unix, 0))
+			if err != nil {
+				t.Fatalf("GenerateTokenAt(%s, %d) failed: %v", algorithm, tt.unix, err)
+			}
+			if token != expected {
+				t.Errorf("GenerateTokenAt(%s, %d) = %s, expected %s", algorithm, tt.unix, token, expected)
+			}
+		}
+	}
+}
+
+func TestTOTPWithClock(t *testing.T) {
+	key := []byte("12345678901234567890")
+	clock := FixedClock(time.Unix(1234567890, 0))
+
+	token, err := TOTP(key, clock, Params{Algorithm: "sha1", Digits: 8})
+	if err != nil {
+		t.Fatalf("TOTP failed: %v", err)
+	}
+	if token != "89005924" {
+		t.Errorf("Expected 89005924, got %s", token)
+	}
+
+	// Six digits are the low six digits of the same truncated value.
+	token, err = TOTP(key, clock, Params{})
+	if err != nil {
+		t.Fatalf("TOTP failed: %v", err)
+	}
+	if token != "005924" {
+		t.Errorf("Expected 005924, got %s", token)
+	}
+}
+
+func TestHOTPRFC4226Truncation(t *testing.T) {
+	// RFC 4226 Appendix D lists the 31-bit truncated values; with Digits 8
+	// the low eight decimal digits must match.
+	key := []byte("12345678901234567890")
+	truncated := []uint32{
+		1284755224, 1094287082, 137359152, 1726969429, 1640338314,
+		868254676, 1918287922, 82162583, 673399871, 645520489,
+	}
+
+	for counter, value := range truncated {
+		token, err := HOTP(key, uint64(counter), Params{Digits: 8})
+		if err != nil {
+			t.Fatalf("HOTP(%d) failed: %v", counter, err)
+		}
+		if expected := formatDecimal(value, 8); token != expected {
+			t.Errorf("HOTP(%d) = %s, expected %s", counter, token, expected)
+		}
+	}
+}
+
+func TestHOTPAlphabet(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	// Counter 0 truncates to 1284755224; in base 2 ("01") the low bits come
+	// first.
+	token, err := HOTP(key, 0, Params{Digits: 8, Alphabet: "01"})
+	if err != nil {
+		t.Fatalf("HOTP failed: %v", err)
+	}
+
+	expected := ""
+	for value, i := uint32(1284755224), 0; i < 8; i++ {
+		expected += string("01"[value%2])
+		value /= 2
+	}
+	if token != expected {
+		t.Errorf("Expected %s, got %s", expected, token)
+	}
+}
+
+func TestParamsCounter(t *testing.T) {
+	tests := []struct {
+		params   Params
+		unix     int64
+		expected uint64
+	}{
+		{Params{}, 59, 1},
+		{Params{Period: 60}, 59, 0},
+		{Params{T0: 30}, 59, 0},
+		{Params{}, 1111111109, 37037036},
+	}
+
+	for _, tt := range tests {
+		counter, err := tt.params.Counter(time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("Counter(%+v, %d) failed: %v", tt.params, tt.unix, err)
+		}
+		if counter != tt.expected {
+			t.Errorf("Counter(%+v, %d) = %d, expected %d", tt.params, tt.unix, counter, tt.expected)
+		}
+	}
+
+	if _, err := (Params{T0: 100}).Counter(time.Unix(50, 0)); err == nil {
+		t.Error("Expected error for a time before T0")
+	}
+}
+
+func TestGenerateTokenAtPeriodAndT0(t *testing.T) {
+	base, err := GenerateTokenAt(rfcSecretSHA1, Params{Digits: 8}, time.Unix(59, 0))
+	if err != nil {
+		t.Fatalf("GenerateTokenAt failed: %v", err)
+	}
+
+	shifted, err := GenerateTokenAt(rfcSecretSHA1, Params{Digits: 8, T0: 30}, time.Unix(89, 0))
+	if err != nil {
+		t.Fatalf("GenerateTokenAt with T0 failed: %v", err)
+	}
+	if shifted != base {
+		t.Errorf("Expected T0 to shift the counter, got %s and %s", shifted, base)
+	}
+
+	// With a 60 second period, t=59 falls in counter 0 rather than counter 1.
+	long, err := GenerateTokenAt(rfcSecretSHA1, Params{Digits: 8, Period: 60}, time.Unix(59, 0))
+	if err != nil {
+		t.Fatalf("GenerateTokenAt with period failed: %v", err)
+	}
+	counterZero, err := GenerateTokenAt(rfcSecretSHA1, Params{Digits: 8}, time.Unix(0, 0))
+	if err != nil {
+		t.Fatalf("GenerateTokenAt failed: %v", err)
+	}
+	if long != counterZero {
+		t.Errorf("Expected 60s period at t=59 to match counter 0, got %s and %s", long, counterZero)
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	valid := []Params{
+		{},
+		{Algorithm: "SHA256", Digits: 8, Period: 60},
+		{Algorithm: "sha512", Digits: 7, T0: 100},
+		{Digits: 5, Alphabet: "23456789BCDFGHJKMNPQRTVWXY"},
+	}
+	for _, params := range valid {
+		if err := params.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", params, err)
+		}
+	}
+
+	invalid := []Params{
+		{Algorithm: "MD4"},
+		{Alphabet: "A"},
+		{Alphabet: "AB", Digits: 11},
+		{Digits: 5},
+		{Digits: 9},
+		{Period: -30},
+		{T0: -1},
+	}
+	for _, params := range invalid {
+		if err := params.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", params)
+		}
+	}
+}
+
+func TestGenerateHOTP(t *testing.T) {
+	// RFC 4226 Appendix D test values.
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, want := range expected {
+		token, err := GenerateHOTP(rfcSecretSHA1, uint64(counter), Params{})
+		if err != nil {
+			t.Fatalf("GenerateHOTP(%d) failed: %v", counter, err)
+		}
+		if token != want {
+			t.Errorf("GenerateHOTP(%d) = %s, expected %s", counter, token, want)
+		}
+	}
+}
+
+func TestGenerateHOTPWithInvalidSecret(t *testing.T) {
+	if _, err := GenerateHOTP("invalid-secret", 0, Params{}); err == nil {
+		t.Error("Expected error when generating HOTP token with invalid secret")
+	}
+}
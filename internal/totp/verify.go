@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Generator produces the code valid at a given time.
+type Generator func(t time.Time) (string, error)
+
+// Verify checks code against the codes generated for the current step and up
+// to window steps before and after it. Closer steps are tried first. It
+// returns the matching step offset (negative means the code is from a past
+// step) and whether a match was found.
+func Verify(code string, t time.Time, period, window int, generate Generator) (int, bool, error) {
+	if period <= 0 {
+		period = DefaultPeriod
+	}
+	if window < 0 {
+		return 0, false, fmt.Errorf("invalid window: %d", window)
+	}
+
+	code = strings.TrimSpace(code)
+	for _, offset := range offsets(window) {
+		expected, err := generate(t.Add(time.Duration(offset*period) * time.Second))
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return offset, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// VerifyHOTP checks code against the counters from counter to counter+window
+// and returns the offset of the matching counter.
+func VerifyHOTP(secret, code string, counter uint64, window int, params Params) (int, bool, error) {
+	if window < 0 {
+		return 0, false, fmt.Errorf("invalid window: %d", window)
+	}
+
+	code = strings.TrimSpace(code)
+	for offset := 0; offset <= window; offset++ {
+		expected, err := GenerateHOTP(secret, counter+uint64(offset), params)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return offset, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func offsets(window int) []int {
+	result := []int{0}
+	for i := 1; i <= window; i++ {
+		result = append(result, -i, i)
+	}
+	return result
+}
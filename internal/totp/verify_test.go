@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	generate := func(at time.Time) (string, error) {
+		return GenerateTokenAt(rfcSecretSHA1, Params{Digits: 8}, at)
+	}
+
+	tests := []struct {
+		code   string
+		window int
+		offset int
+		ok     bool
+	}{
+		{"07081804", 0, 0, true},
+		{" 07081804 ", 1, 0, true},
+		{mustGenerate(t, generate, now.Add(-30*time.Second)), 1, -1, true},
+		{mustGenerate(t, generate, now.Add(60*time.Second)), 2, 2, true},
+		{mustGenerate(t, generate, now.Add(60*time.Second)), 1, 0, false},
+		{"00000000", 3, 0, false},
+	}
+
+	for _, tt := range tests {
+		offset, ok, err := Verify(tt.code, now, 30, tt.window, generate)
+		if err != nil {
+			t.Fatalf("Verify(%s) failed: %v", tt.code, err)
+		}
+		if ok != tt.ok || offset != tt.offset {
+			t.Errorf("Verify(%s, window %d) = (%d, %v), expected (%d, %v)", tt.code, tt.window, offset, ok, tt.offset, tt.ok)
+		}
+	}
+
+	if _, _, err := Verify("123456", now, 30, -1, generate); err == nil {
+		t.Error("Expected error for negative window")
+	}
+}
+
+func TestVerifyHOTP(t *testing.T) {
+	// RFC 4226 values for counters 3 and 5.
+	offset, ok, err := VerifyHOTP(rfcSecretSHA1, "969429", 3, 0, Params{})
+	if err != nil || !ok || offset != 0 {
+		t.Errorf("Expected counter 3 to match at offset 0, got (%d, %v, %v)", offset, ok, err)
+	}
+
+	offset, ok, err = VerifyHOTP(rfcSecretSHA1, "254676", 3, 2, Params{})
+	if err != nil || !ok || offset != 2 {
+		t.Errorf("Expected counter 5 to match at offset 2, got (%d, %v, %v)", offset, ok, err)
+	}
+
+	_, ok, err = VerifyHOTP(rfcSecretSHA1, "254676", 3, 1, Params{})
+	if err != nil || ok {
+		t.Errorf("Expected counter 5 to be outside window 1, got (%v, %v)", ok, err)
+	}
+}
+
+func mustGenerate(t *testing.T, generate Generator, at time.Time) string {
+	t.Helper()
+	code, err := generate(at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
@@ -0,0 +1,36 @@
+package totp
+
+import "time"
+
+// StepBounds returns the start and end of the time step that contains t for
+// the given period (in seconds) and T0.
+func StepBounds(t time.Time, period int, t0 int64) (time.Time, time.Time) {
+	if period <= 0 {
+		period = DefaultPeriod
+	}
+
+	elapsed := t.Unix() - t0
+	step := elapsed / int64(period)
+	if elapsed < 0 && elapsed%int64(period) != 0 {
+		step--
+	}
+
+	start := time.Unix(t0+step*int64(period), 0)
+	return start, start.Add(time.Duration(period) * time.Second)
+}
+
+// Remaining returns how long the code valid at t stays valid.
+func Remaining(t time.Time, period int, t0 int64) time.Duration {
+	_, end := StepBounds(t, period, t0)
+	return end.Sub(t)
+}
+
+// FreshAt returns t if the code valid at t stays valid for at least
+// minValidity, or the start of the next step otherwise.
+func FreshAt(t time.Time, period int, t0 int64, minValidity time.Duration) time.Time {
+	_, end := StepBounds(t, period, t0)
+	if end.Sub(t) >= minValidity {
+		return t
+	}
+	return end
+}
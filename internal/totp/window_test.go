@@ -0,0 +1,68 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStepBounds(t *testing.T) {
+	tests := []struct {
+		unix   int64
+		period int
+		t0     int64
+		start  int64
+		end    int64
+	}{
+		{59, 30, 0, 30, 60},
+		{60, 30, 0, 60, 90},
+		{59, 0, 0, 30, 60},
+		{59, 60, 0, 0, 60},
+		{100, 30, 15, 75, 105},
+		{10, 30, 15, -15, 15},
+	}
+
+	for _, tt := range tests {
+		start, end := StepBounds(time.Unix(tt.unix, 0), tt.period, tt.t0)
+		if start.Unix() != tt.start || end.Unix() != tt.end {
+			t.Errorf("StepBounds(%d, %d, %d) = [%d, %d), expected [%d, %d)",
+				tt.unix, tt.period, tt.t0, start.Unix(), end.Unix(), tt.start, tt.end)
+		}
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	at := time.Unix(1111111109, 500*int64(time.Millisecond))
+	if remaining := Remaining(at, 30, 0); remaining != 500*time.Millisecond {
+		t.Errorf("Expected 500ms remaining, got %v", remaining)
+	}
+
+	if remaining := Remaining(time.Unix(60, 0), 30, 0); remaining != 30*time.Second {
+		t.Errorf("Expected a full period at the start of a step, got %v", remaining)
+	}
+}
+
+func TestFreshAt(t *testing.T) {
+	tests := []struct {
+		unix        int64
+		minValidity time.Duration
+		expected    int64
+	}{
+		{40, 5 * time.Second, 40},
+		{55, 5 * time.Second, 55},
+		{56, 5 * time.Second, 60},
+		{59, 5 * time.Second, 60},
+		{59, 0, 59},
+	}
+
+	for _, tt := range tests {
+		fresh := FreshAt(time.Unix(tt.unix, 0), 30, 0, tt.minValidity)
+		if fresh.Unix() != tt.expected {
+			t.Errorf("FreshAt(%d, %v) = %d, expected %d", tt.unix, tt.minValidity, fresh.Unix(), tt.expected)
+		}
+	}
+
+	// t=26 is near the end of a 30 second step but early in a 60 second one.
+	if fresh := FreshAt(time.Unix(26, 0), 60, 0, 5*time.Second); fresh.Unix() != 26 {
+		t.Errorf("Expected period-aware FreshAt to keep t=26, got %d", fresh.Unix())
+	}
+}
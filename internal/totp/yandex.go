@@ -0,0 +1,88 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	// YandexPeriod is the validity of a Yandex Key code in seconds.
+	YandexPeriod = 30
+	// YandexDigits is the length of a Yandex Key code.
+	YandexDigits = 8
+
+	// yandexSecretLength is the size of the key itself. Secrets exported by
+	// Yandex are 26 bytes long; the trailing bytes carry a checksum.
+	yandexSecretLength   = 16
+	yandexExportedLength = 26
+	yandexMinPIN         = 4
+	yandexMaxPIN         = 16
+)
+
+// GenerateYandexToken generates a Yandex Key code. The HMAC-SHA256 key is
+// SHA256(PIN || secret), with a leading zero byte dropped, and the truncated
+// 63-bit value is written as eight lowercase letters.
+func GenerateYandexToken(secret, pin string, t time.Time) (string, error) {
+	key, err := decodeYandexSecret(secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate Yandex token: %w", err)
+	}
+	if err := ValidateYandexPIN(pin); err != nil {
+		return "", fmt.Errorf("failed to generate Yandex token: %w", err)
+	}
+
+	keyHash := sha256.Sum256(append([]byte(pin), key[:yandexSecretLength]...))
+	hmacKey := keyHash[:]
+	if hmacKey[0] == 0 {
+		hmacKey = hmacKey[1:]
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(t.Unix()/YandexPeriod))
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint64(sum[offset:offset+8]) & 0x7fffffffffffffff
+
+	code := make([]byte, YandexDigits)
+	for i := YandexDigits - 1; i >= 0; i-- {
+		code[i] = 'a' + byte(value%26)
+		value /= 26
+	}
+	return string(code), nil
+}
+
+// ValidateYandexSecret checks that a base32 secret has the size of a Yandex
+// Key secret.
+func ValidateYandexSecret(secret string) error {
+	_, err := decodeYandexSecret(secret)
+	return err
+}
+
+func decodeYandexSecret(secret string) ([]byte, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != yandexSecretLength && len(key) != yandexExportedLength {
+		return nil, fmt.Errorf("invalid Yandex secret: must be %d or %d bytes", yandexSecretLength, yandexExportedLength)
+	}
+	return key, nil
+}
+
+// ValidateYandexPIN checks that a Yandex Key PIN has 4 to 16 digits.
+func ValidateYandexPIN(pin string) error {
+	if err := ValidatePIN(pin); err != nil {
+		return err
+	}
+	if len(pin) < yandexMinPIN || len(pin) > yandexMaxPIN {
+		return fmt.Errorf("PIN must have %d to %d digits", yandexMinPIN, yandexMaxPIN)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateYandexToken(t *testing.T) {
+	// Vectors captured from the Yandex Key application.
+	tests := []struct {
+		pin      string
+		secret   string
+		unix     int64
+		expected string
+	}{
+		{"5239", "6SB2IKNM6OBZPAVBVTOHDKS4FAAAAAAADFUTQMBTRY", 1641559648, "umozdicq"},
+		{"7586", "LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", 1581064020, "oactmacq"},
+		{"7586", "LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", 1581090810, "wemdwrix"},
+		{"5210481216086702", "JBGSAU4G7IEZG6OY4UAXX62JU4AAAAAAHTSG4HRZPI", 1581091469, "dfrpywob"},
+		{"5210481216086702", "JBGSAU4G7IEZG6OY4UAXX62JU4AAAAAAHTSG4HRZPI", 1581093059, "vunyprpd"},
+	}
+
+	for _, tt := range tests {
+		token, err := GenerateYandexToken(tt.secret, tt.pin, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateYandexToken(%s, %d) failed: %v", tt.secret, tt.unix, err)
+		}
+		if token != tt.expected {
+			t.Errorf("GenerateYandexToken(%s, %s, %d) = %s, expected %s", tt.secret, tt.pin, tt.unix, token, tt.expected)
+		}
+	}
+}
+
+func TestGenerateYandexTokenInvalid(t *testing.T) {
+	invalid := []struct {
+		secret string
+		pin    string
+	}{
+		{"LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", ""},
+		{"LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", "123"},
+		{"LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", "12345678901234567"},
+		{"LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", "75a6"},
+		{"JBSWY3DPEHPK3PXP", "7586"},
+	}
+
+	for _, tt := range invalid {
+		if _, err := GenerateYandexToken(tt.secret, tt.pin, time.Unix(0, 0)); err == nil {
+			t.Errorf("Expected GenerateYandexToken(%q, %q) to fail", tt.secret, tt.pin)
+		}
+	}
+}
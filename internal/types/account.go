@@ -1,6 +1,59 @@
 package types
 
+import "time"
+
+const (
+	AccountTypeTOTP   = "totp"
+	AccountTypeHOTP   = "hotp"
+	AccountTypeSteam  = "steam"
+	AccountTypeMOTP   = "motp"
+	AccountTypeYandex = "yandex"
+	AccountTypeOCRA   = "ocra"
+)
+
 type Account struct {
-	Name   string `json:"name"`
-	Secret string `json:"secret"`
+	Name      string `json:"name"`
+	Secret    string `json:"secret"`
+	Issuer    string `json:"issuer,omitempty"`
+	Type      string `json:"type,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Digits    int    `json:"digits,omitempty"`
+	Period    int    `json:"period,omitempty"`
+	T0        int64  `json:"t0,omitempty"`
+	Counter   uint64 `json:"counter,omitempty"`
+	// Suite is the RFC 6287 suite of OCRA accounts, e.g.
+	// "OCRA-1:HOTP-SHA1-6:QN08".
+	Suite string `json:"suite,omitempty"`
+	// PIN is combined with the secret by PIN-based tokens such as mOTP and
+	// Yandex Key. It is stored alongside the secret, in the same encrypted
+	// record, and is left empty when the user prefers to be prompted for it.
+	PIN string `json:"pin,omitempty"`
+	// TimeOffsetMillis corrects the clock for this account only, on top of
+	// the global offset.
+	TimeOffsetMillis int64 `json:"time_offset_ms,omitempty"`
+}
+
+// AccountType returns the account type, defaulting to TOTP for accounts
+// stored before the type field existed.
+func (a Account) AccountType() string {
+	if a.Type == "" {
+		return AccountTypeTOTP
+	}
+	return a.Type
+}
+
+// TimeOffset returns the per-account clock offset.
+func (a Account) TimeOffset() time.Duration {
+	return time.Duration(a.TimeOffsetMillis) * time.Millisecond
+}
+
+// NeedsPIN reports whether codes for this account type combine the secret
+// with a PIN.
+func (a Account) NeedsPIN() bool {
+	switch a.AccountType() {
+	case AccountTypeMOTP, AccountTypeYandex:
+		return true
+	default:
+		return false
+	}
 }